@@ -0,0 +1,69 @@
+package databricks
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	errorCodeResourceDoesNotExist  = "RESOURCE_DOES_NOT_EXIST"
+	errorCodeInvalidParameterValue = "INVALID_PARAMETER_VALUE"
+)
+
+// apiError is an error response of the Databricks REST API, such as
+// {"error_code":"INVALID_PARAMETER_VALUE","message":"Cluster 1234-567890-abc123 does not exist"}
+type apiError struct {
+	Status    string `json:"-"`
+	ErrorCode string `json:"error_code"`
+	Message   string `json:"message"`
+}
+
+func (e *apiError) Error() string {
+	if e.ErrorCode == "" {
+		return fmt.Sprintf("%s: %s", e.Status, e.Message)
+	}
+	return fmt.Sprintf("%s (%s): %s", e.ErrorCode, e.Status, e.Message)
+}
+
+// newAPIError converts an error returned by the SDK into an *apiError built from the response body.
+// Errors without a response body, such as network failures, are returned unchanged.
+func newAPIError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	swaggerErr, ok := err.(interface {
+		Body() []byte
+	})
+	if !ok || len(swaggerErr.Body()) == 0 {
+		return err
+	}
+
+	result := &apiError{Status: err.Error()}
+	body := swaggerErr.Body()
+	if jsonErr := json.Unmarshal(body, result); jsonErr != nil || (result.ErrorCode == "" && result.Message == "") {
+		// e.g. an HTML error page from a proxy in front of the workspace
+		return fmt.Errorf("%s: %s", err, strings.TrimSpace(string(body)))
+	}
+
+	return result
+}
+
+// isNotFoundError tells whether the API reported the requested object as missing. The clusters and jobs
+// APIs report unknown IDs as INVALID_PARAMETER_VALUE, so the message is checked for those.
+func isNotFoundError(err error) bool {
+	e, ok := err.(*apiError)
+	if !ok {
+		return false
+	}
+
+	switch e.ErrorCode {
+	case errorCodeResourceDoesNotExist:
+		return true
+	case errorCodeInvalidParameterValue:
+		return strings.Contains(e.Message, "does not exist")
+	}
+
+	return strings.HasPrefix(e.Status, "404")
+}
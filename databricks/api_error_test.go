@@ -0,0 +1,51 @@
+package databricks
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNewAPIError(t *testing.T) {
+	err := newAPIError(testSwaggerError{
+		"400 Bad Request",
+		`{"error_code":"INVALID_PARAMETER_VALUE","message":"Node type r4.huge is not supported"}`,
+	})
+
+	e, ok := err.(*apiError)
+	if !ok {
+		t.Fatalf("expected an *apiError, got %T", err)
+	}
+
+	if e.ErrorCode != errorCodeInvalidParameterValue || e.Message != "Node type r4.huge is not supported" {
+		t.Fatalf("unexpected error %+v", e)
+	}
+
+	if !strings.Contains(err.Error(), "Node type r4.huge is not supported") {
+		t.Fatalf("expected the server message in %q", err)
+	}
+}
+
+func TestNewAPIError_unparseableBody(t *testing.T) {
+	err := newAPIError(testSwaggerError{"502 Bad Gateway", "<html>Bad Gateway</html>\n"})
+
+	if _, ok := err.(*apiError); ok {
+		t.Fatal("an HTML body should not be parsed as an API error")
+	}
+
+	if err.Error() != "502 Bad Gateway: <html>Bad Gateway</html>" {
+		t.Fatalf("unexpected error %q", err)
+	}
+}
+
+func TestNewAPIError_withoutBody(t *testing.T) {
+	original := errors.New("dial tcp: connection refused")
+
+	if err := newAPIError(original); err != original {
+		t.Fatalf("expected the original error, got %q", err)
+	}
+
+	if newAPIError(nil) != nil {
+		t.Fatal("expected nil")
+	}
+}
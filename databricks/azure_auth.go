@@ -0,0 +1,195 @@
+package databricks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// application ID of the Azure Databricks first party service, used as the AAD resource for API tokens
+	azureDatabricksResource = "2ff814a6-3304-4ab8-85cb-cd0e6f879c1d"
+	azureManagementResource = "https://management.core.windows.net/"
+	azureLoginEndpoint      = "https://login.microsoftonline.com"
+
+	// tokens are refreshed this long before they expire, so that a token never expires mid-request
+	azureTokenRefreshWindow = 5 * time.Minute
+)
+
+// azureServicePrincipal holds the AAD application used to authenticate against an Azure Databricks workspace.
+type azureServicePrincipal struct {
+	ClientID            string
+	ClientSecret        string
+	TenantID            string
+	WorkspaceResourceID string
+}
+
+// newAzureServicePrincipal returns nil when no workspace resource ID is configured, meaning token
+// authentication is used instead. Otherwise every other attribute is required.
+func newAzureServicePrincipal(clientID, clientSecret, tenantID, workspaceResourceID string) (*azureServicePrincipal, error) {
+	if workspaceResourceID == "" {
+		return nil, nil
+	}
+
+	var missing []string
+	if clientID == "" {
+		missing = append(missing, "azure_client_id")
+	}
+	if clientSecret == "" {
+		missing = append(missing, "azure_client_secret")
+	}
+	if tenantID == "" {
+		missing = append(missing, "azure_tenant_id")
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("azure_workspace_resource_id is set, but these arguments are missing: %s", strings.Join(missing, ", "))
+	}
+
+	return &azureServicePrincipal{
+		ClientID:            clientID,
+		ClientSecret:        clientSecret,
+		TenantID:            tenantID,
+		WorkspaceResourceID: workspaceResourceID,
+	}, nil
+}
+
+type azureToken struct {
+	AccessToken string
+	ExpiresOn   time.Time
+}
+
+type azureTokenResponse struct {
+	AccessToken      string      `json:"access_token"`
+	ExpiresIn        json.Number `json:"expires_in"`
+	Error            string      `json:"error"`
+	ErrorDescription string      `json:"error_description"`
+}
+
+// azureAuthTransport adds the AAD headers expected by Azure Databricks to every request: a token for the
+// Databricks resource, a token for the Azure management plane and the workspace resource ID. Tokens are
+// fetched with the client credentials grant and cached until they are about to expire.
+type azureAuthTransport struct {
+	principal     azureServicePrincipal
+	loginEndpoint string
+	transport     http.RoundTripper
+
+	mu     sync.Mutex
+	tokens map[string]*azureToken
+}
+
+func newAzureAuthTransport(principal azureServicePrincipal, transport http.RoundTripper) *azureAuthTransport {
+	return &azureAuthTransport{
+		principal:     principal,
+		loginEndpoint: azureLoginEndpoint,
+		transport:     transport,
+		tokens:        make(map[string]*azureToken),
+	}
+}
+
+func (t *azureAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	platformToken, err := t.token(azureDatabricksResource)
+	if err != nil {
+		return nil, err
+	}
+
+	managementToken, err := t.token(azureManagementResource)
+	if err != nil {
+		return nil, err
+	}
+
+	// a RoundTripper must not modify the request it was given
+	authorized := cloneRequest(req)
+	authorized.Header.Set("Authorization", "Bearer "+platformToken)
+	authorized.Header.Set("X-Databricks-Azure-SP-Management-Token", managementToken)
+	authorized.Header.Set("X-Databricks-Azure-Workspace-Resource-Id", t.principal.WorkspaceResourceID)
+
+	return t.transport.RoundTrip(authorized)
+}
+
+func (t *azureAuthTransport) token(resource string) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if cached, ok := t.tokens[resource]; ok && time.Now().Add(azureTokenRefreshWindow).Before(cached.ExpiresOn) {
+		return cached.AccessToken, nil
+	}
+
+	token, err := t.fetchToken(resource)
+	if err != nil {
+		return "", fmt.Errorf("failed to obtain Azure AD token for %s: %s", resource, err)
+	}
+	t.tokens[resource] = token
+
+	return token.AccessToken, nil
+}
+
+func (t *azureAuthTransport) fetchToken(resource string) (*azureToken, error) {
+	endpoint := fmt.Sprintf("%s/%s/oauth2/token", t.loginEndpoint, url.PathEscape(t.principal.TenantID))
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {t.principal.ClientID},
+		"client_secret": {t.principal.ClientSecret},
+		"resource":      {resource},
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	// token requests go straight to the underlying transport, they must not be authorized themselves
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var tokenResponse azureTokenResponse
+	if err := json.Unmarshal(body, &tokenResponse); err != nil {
+		return nil, fmt.Errorf("%s: %s", resp.Status, body)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		if tokenResponse.ErrorDescription != "" {
+			return nil, fmt.Errorf("%s: %s", tokenResponse.Error, tokenResponse.ErrorDescription)
+		}
+		return nil, errors.New(resp.Status)
+	}
+
+	if tokenResponse.AccessToken == "" {
+		return nil, errors.New("response did not contain an access token")
+	}
+
+	expiresIn, err := strconv.Atoi(tokenResponse.ExpiresIn.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid expires_in %q: %s", tokenResponse.ExpiresIn, err)
+	}
+
+	return &azureToken{
+		AccessToken: tokenResponse.AccessToken,
+		ExpiresOn:   time.Now().Add(time.Duration(expiresIn) * time.Second),
+	}, nil
+}
+
+func cloneRequest(req *http.Request) *http.Request {
+	clone := new(http.Request)
+	*clone = *req
+	clone.Header = make(http.Header, len(req.Header))
+	for k, v := range req.Header {
+		clone.Header[k] = append([]string(nil), v...)
+	}
+	return clone
+}
@@ -0,0 +1,191 @@
+package databricks
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var testAzureServicePrincipal = azureServicePrincipal{
+	ClientID:            "client-id",
+	ClientSecret:        "client-secret",
+	TenantID:            "tenant-id",
+	WorkspaceResourceID: "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Databricks/workspaces/ws",
+}
+
+// testAzureLoginServer fakes the AAD v1 token endpoint, issuing tokens named after the requested
+// resource and counting how many were issued.
+type testAzureLoginServer struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	issued    map[string]int
+	expiresIn string
+}
+
+func newTestAzureLoginServer(t *testing.T, expiresIn string) *testAzureLoginServer {
+	s := &testAzureLoginServer{issued: make(map[string]int), expiresIn: expiresIn}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/tenant-id/oauth2/token" {
+			t.Errorf("unexpected token request path %s", r.URL.Path)
+		}
+
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("err: %s", err)
+		}
+
+		if r.PostForm.Get("client_secret") != "client-secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":"invalid_client","error_description":"AADSTS7000215: Invalid client secret is provided."}`)
+			return
+		}
+
+		resource := r.PostForm.Get("resource")
+
+		s.mu.Lock()
+		s.issued[resource]++
+		s.mu.Unlock()
+
+		fmt.Fprintf(w, `{"token_type":"Bearer","expires_in":"%s","access_token":"token-for-%s"}`, s.expiresIn, resource)
+	}))
+
+	return s
+}
+
+func (s *testAzureLoginServer) issuedFor(resource string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.issued[resource]
+}
+
+// testAzureWorkspaceServer records the headers of the last request it received.
+func testAzureWorkspaceServer(headers *http.Header) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*headers = r.Header
+		fmt.Fprint(w, `{}`)
+	}))
+}
+
+func testAzureClient(principal azureServicePrincipal, loginEndpoint string) *http.Client {
+	transport := newAzureAuthTransport(principal, http.DefaultTransport)
+	transport.loginEndpoint = loginEndpoint
+	return &http.Client{Transport: transport}
+}
+
+func TestAzureAuthTransport_setsHeaders(t *testing.T) {
+	login := newTestAzureLoginServer(t, "3599")
+	defer login.Close()
+
+	var headers http.Header
+	workspace := testAzureWorkspaceServer(&headers)
+	defer workspace.Close()
+
+	client := testAzureClient(testAzureServicePrincipal, login.URL)
+
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get(workspace.URL + "/api/2.0/clusters/list")
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		resp.Body.Close()
+	}
+
+	if v := headers.Get("Authorization"); v != "Bearer token-for-"+azureDatabricksResource {
+		t.Fatalf("unexpected Authorization header %q", v)
+	}
+
+	if v := headers.Get("X-Databricks-Azure-SP-Management-Token"); v != "token-for-"+azureManagementResource {
+		t.Fatalf("unexpected management token header %q", v)
+	}
+
+	if v := headers.Get("X-Databricks-Azure-Workspace-Resource-Id"); v != testAzureServicePrincipal.WorkspaceResourceID {
+		t.Fatalf("unexpected workspace resource ID header %q", v)
+	}
+
+	// tokens are valid for an hour, so they should have been fetched once and then cached
+	if n := login.issuedFor(azureDatabricksResource); n != 1 {
+		t.Fatalf("expected 1 Databricks token to be issued, got %d", n)
+	}
+
+	if n := login.issuedFor(azureManagementResource); n != 1 {
+		t.Fatalf("expected 1 management token to be issued, got %d", n)
+	}
+}
+
+func TestAzureAuthTransport_refreshesExpiringTokens(t *testing.T) {
+	// tokens expiring within the refresh window are fetched again on every request
+	login := newTestAzureLoginServer(t, "60")
+	defer login.Close()
+
+	var headers http.Header
+	workspace := testAzureWorkspaceServer(&headers)
+	defer workspace.Close()
+
+	client := testAzureClient(testAzureServicePrincipal, login.URL)
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(workspace.URL)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		resp.Body.Close()
+	}
+
+	if n := login.issuedFor(azureDatabricksResource); n != 2 {
+		t.Fatalf("expected the Databricks token to be refreshed, got %d issued", n)
+	}
+}
+
+func TestAzureAuthTransport_invalidCredentials(t *testing.T) {
+	login := newTestAzureLoginServer(t, "3599")
+	defer login.Close()
+
+	var headers http.Header
+	workspace := testAzureWorkspaceServer(&headers)
+	defer workspace.Close()
+
+	principal := testAzureServicePrincipal
+	principal.ClientSecret = "wrong"
+
+	_, err := testAzureClient(principal, login.URL).Get(workspace.URL)
+	if err == nil {
+		t.Fatal("expected an error for invalid client credentials")
+	}
+
+	if !strings.Contains(err.Error(), "AADSTS7000215") {
+		t.Fatalf("expected the AAD error description in %q", err)
+	}
+
+	if headers != nil {
+		t.Fatal("no request should reach the workspace without a token")
+	}
+}
+
+func TestNewAzureServicePrincipal(t *testing.T) {
+	principal, err := newAzureServicePrincipal("", "", "", "")
+	if err != nil || principal != nil {
+		t.Fatalf("expected no service principal without a workspace resource ID, got %v, %v", principal, err)
+	}
+
+	_, err = newAzureServicePrincipal("client-id", "", "", "/subscriptions/sub")
+	if err == nil {
+		t.Fatal("expected an error for an incomplete service principal")
+	}
+
+	if !strings.Contains(err.Error(), "azure_client_secret, azure_tenant_id") {
+		t.Fatalf("expected the missing arguments in %q", err)
+	}
+
+	principal, err = newAzureServicePrincipal("client-id", "client-secret", "tenant-id", "/subscriptions/sub")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if principal.TenantID != "tenant-id" {
+		t.Fatalf("unexpected service principal %+v", principal)
+	}
+}
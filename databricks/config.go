@@ -0,0 +1,190 @@
+package databricks
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	defaultConfigFile = "~/.databrickscfg"
+	defaultProfile    = "DEFAULT"
+)
+
+// providerCredentials holds the workspace host and token, and where each of them was found.
+type providerCredentials struct {
+	Host        string
+	HostSource  string
+	Token       string
+	TokenSource string
+}
+
+// resolveCredentials looks up the workspace host and token. Each value is taken from the first
+// source that sets it:
+//  1. the domain and token arguments of the provider block
+//  2. the DATABRICKS_HOST and DATABRICKS_TOKEN environment variables
+//  3. the host and token keys of a profile in the Databricks CLI config file
+//
+// The profile defaults to DEFAULT and the config file to ~/.databrickscfg. A missing config file or
+// profile is only an error when the profile was set explicitly. The token may be left empty when
+// requireToken is false, e.g. when Azure AD authentication is used.
+func resolveCredentials(domain, token, profile, configFile string, requireToken bool) (*providerCredentials, error) {
+	creds := &providerCredentials{}
+
+	if domain != "" {
+		creds.Host, creds.HostSource = domain, "provider configuration"
+	} else if v := os.Getenv("DATABRICKS_HOST"); v != "" {
+		creds.Host, creds.HostSource = v, "environment variable DATABRICKS_HOST"
+	}
+
+	if token != "" {
+		creds.Token, creds.TokenSource = token, "provider configuration"
+	} else if v := os.Getenv("DATABRICKS_TOKEN"); v != "" {
+		creds.Token, creds.TokenSource = v, "environment variable DATABRICKS_TOKEN"
+	}
+
+	if creds.Host == "" || (creds.Token == "" && requireToken) {
+		explicitProfile := profile != ""
+		if !explicitProfile {
+			profile = defaultProfile
+		}
+		if configFile == "" {
+			configFile = defaultConfigFile
+		}
+
+		path, err := expandHomeDir(configFile)
+		if err != nil {
+			return nil, err
+		}
+
+		profiles, err := readDatabricksConfigFile(path)
+		if err != nil && (explicitProfile || !os.IsNotExist(err)) {
+			return nil, fmt.Errorf("failed to read Databricks config file %s: %s", path, err)
+		}
+
+		section, ok := profiles[profile]
+		if !ok && explicitProfile {
+			return nil, fmt.Errorf("profile %q not found in Databricks config file %s", profile, path)
+		}
+
+		source := fmt.Sprintf("profile %q in %s", profile, path)
+		if v := section["host"]; creds.Host == "" && v != "" {
+			creds.Host, creds.HostSource = v, source
+		}
+		if v := section["token"]; creds.Token == "" && v != "" {
+			creds.Token, creds.TokenSource = v, source
+		}
+	}
+
+	if creds.Host == "" {
+		return nil, errors.New("no Databricks host configured: set domain in the provider block, " +
+			"DATABRICKS_HOST, or host in a ~/.databrickscfg profile")
+	}
+
+	if creds.Token == "" && requireToken {
+		return nil, errors.New("no Databricks token configured: set token in the provider block, " +
+			"DATABRICKS_TOKEN, or token in a ~/.databrickscfg profile")
+	}
+
+	creds.Host = normalizeHost(creds.Host)
+
+	return creds, nil
+}
+
+// readDatabricksConfigFile parses the INI-style config file used by the Databricks CLI into a map of
+// profile name to its keys.
+func readDatabricksConfigFile(path string) (map[string]map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	profiles := make(map[string]map[string]string)
+	var section map[string]string
+
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			if _, ok := profiles[name]; !ok {
+				profiles[name] = make(map[string]string)
+			}
+			section = profiles[name]
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 || section == nil {
+			return nil, fmt.Errorf("line %d: expected a [profile] header or a key = value pair", lineNumber)
+		}
+		section[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return profiles, nil
+}
+
+func expandHomeDir(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+
+	home := os.Getenv("HOME")
+	if home == "" {
+		u, err := user.Current()
+		if err != nil {
+			return "", fmt.Errorf("failed to expand %s: %s", path, err)
+		}
+		home = u.HomeDir
+	}
+
+	return filepath.Join(home, path[1:]), nil
+}
+
+// normalizeHost accepts both a bare workspace domain and a full URL, as written by the Databricks CLI.
+func normalizeHost(host string) string {
+	if !strings.Contains(host, "://") {
+		host = "https://" + host
+	}
+	return strings.TrimSuffix(host, "/")
+}
+
+// workspaceCloudDomains maps the domains of workspaces to the cloud they run on.
+var workspaceCloudDomains = map[string]string{
+	".cloud.databricks.com": "aws",
+	".azuredatabricks.net":  "azure",
+	".gcp.databricks.com":   "gcp",
+}
+
+// workspaceCloud returns the cloud a workspace runs on from its normalized host, e.g. azure for
+// https://adb-1234567890123456.7.azuredatabricks.net, or "" for hosts it does not recognise such as custom
+// domains.
+func workspaceCloud(host string) string {
+	u, err := url.Parse(host)
+	if err != nil {
+		return ""
+	}
+
+	hostname := strings.ToLower(u.Hostname())
+	for domain, cloud := range workspaceCloudDomains {
+		if strings.HasSuffix(hostname, domain) {
+			return cloud
+		}
+	}
+	return ""
+}
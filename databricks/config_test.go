@@ -0,0 +1,240 @@
+package databricks
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testDatabricksConfig = `
+; written by databricks configure
+[DEFAULT]
+host = https://default.cloud.databricks.com/
+token = default-token
+
+[staging]
+host  = staging.cloud.databricks.com
+token = staging-token
+
+# profile without a token
+[host-only]
+host = https://host-only.cloud.databricks.com
+`
+
+func testWriteDatabricksConfig(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "databrickscfg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, ".databrickscfg")
+	if err := ioutil.WriteFile(path, []byte(testDatabricksConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func testUnsetCredentialsEnv(t *testing.T) func() {
+	saved := make(map[string]string)
+	for _, k := range []string{"DATABRICKS_HOST", "DATABRICKS_TOKEN"} {
+		if v, ok := os.LookupEnv(k); ok {
+			saved[k] = v
+		}
+		os.Unsetenv(k)
+	}
+
+	return func() {
+		for _, k := range []string{"DATABRICKS_HOST", "DATABRICKS_TOKEN"} {
+			if v, ok := saved[k]; ok {
+				os.Setenv(k, v)
+			} else {
+				os.Unsetenv(k)
+			}
+		}
+	}
+}
+
+func TestReadDatabricksConfigFile(t *testing.T) {
+	path, cleanup := testWriteDatabricksConfig(t)
+	defer cleanup()
+
+	profiles, err := readDatabricksConfigFile(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if len(profiles) != 3 {
+		t.Fatalf("expected 3 profiles, got %d: %v", len(profiles), profiles)
+	}
+
+	if v := profiles["staging"]["token"]; v != "staging-token" {
+		t.Fatalf("expected staging token %q, got %q", "staging-token", v)
+	}
+
+	if _, ok := profiles["host-only"]["token"]; ok {
+		t.Fatal("host-only profile should not have a token")
+	}
+}
+
+func TestReadDatabricksConfigFile_malformed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "databrickscfg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, ".databrickscfg")
+	if err := ioutil.WriteFile(path, []byte("host = https://no-section\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := readDatabricksConfigFile(path); err == nil {
+		t.Fatal("expected an error for a key outside of any profile")
+	}
+}
+
+func TestResolveCredentials_providerConfigurationWins(t *testing.T) {
+	path, cleanup := testWriteDatabricksConfig(t)
+	defer cleanup()
+	defer testUnsetCredentialsEnv(t)()
+
+	os.Setenv("DATABRICKS_HOST", "https://env.cloud.databricks.com")
+	os.Setenv("DATABRICKS_TOKEN", "env-token")
+
+	creds, err := resolveCredentials("https://explicit.cloud.databricks.com", "explicit-token", "", path, true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if creds.Host != "https://explicit.cloud.databricks.com" || creds.Token != "explicit-token" {
+		t.Fatalf("unexpected credentials: %+v", creds)
+	}
+
+	if creds.HostSource != "provider configuration" || creds.TokenSource != "provider configuration" {
+		t.Fatalf("unexpected sources: %+v", creds)
+	}
+}
+
+func TestResolveCredentials_environment(t *testing.T) {
+	path, cleanup := testWriteDatabricksConfig(t)
+	defer cleanup()
+	defer testUnsetCredentialsEnv(t)()
+
+	os.Setenv("DATABRICKS_HOST", "env.cloud.databricks.com")
+	os.Setenv("DATABRICKS_TOKEN", "env-token")
+
+	creds, err := resolveCredentials("", "", "", path, true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if creds.Host != "https://env.cloud.databricks.com" || creds.Token != "env-token" {
+		t.Fatalf("unexpected credentials: %+v", creds)
+	}
+
+	if creds.TokenSource != "environment variable DATABRICKS_TOKEN" {
+		t.Fatalf("unexpected token source: %s", creds.TokenSource)
+	}
+}
+
+func TestResolveCredentials_profile(t *testing.T) {
+	path, cleanup := testWriteDatabricksConfig(t)
+	defer cleanup()
+	defer testUnsetCredentialsEnv(t)()
+
+	creds, err := resolveCredentials("", "", "", path, true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if creds.Host != "https://default.cloud.databricks.com" || creds.Token != "default-token" {
+		t.Fatalf("unexpected credentials from DEFAULT profile: %+v", creds)
+	}
+
+	creds, err = resolveCredentials("", "", "staging", path, true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if creds.Host != "https://staging.cloud.databricks.com" || creds.Token != "staging-token" {
+		t.Fatalf("unexpected credentials from staging profile: %+v", creds)
+	}
+}
+
+func TestResolveCredentials_mixedSources(t *testing.T) {
+	path, cleanup := testWriteDatabricksConfig(t)
+	defer cleanup()
+	defer testUnsetCredentialsEnv(t)()
+
+	os.Setenv("DATABRICKS_TOKEN", "env-token")
+
+	creds, err := resolveCredentials("", "", "host-only", path, true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if creds.Host != "https://host-only.cloud.databricks.com" || creds.Token != "env-token" {
+		t.Fatalf("unexpected credentials: %+v", creds)
+	}
+}
+
+func TestResolveCredentials_missingProfile(t *testing.T) {
+	path, cleanup := testWriteDatabricksConfig(t)
+	defer cleanup()
+	defer testUnsetCredentialsEnv(t)()
+
+	if _, err := resolveCredentials("", "", "production", path, true); err == nil {
+		t.Fatal("expected an error for a profile missing from the config file")
+	}
+
+	if _, err := resolveCredentials("", "", "host-only", path, true); err == nil {
+		t.Fatal("expected an error for a profile without a token")
+	}
+
+	creds, err := resolveCredentials("", "", "host-only", path, false)
+	if err != nil {
+		t.Fatalf("a missing token should be accepted when it is not required, got: %s", err)
+	}
+	if creds.Host != "https://host-only.cloud.databricks.com" {
+		t.Fatalf("unexpected credentials: %+v", creds)
+	}
+}
+
+func TestResolveCredentials_missingConfigFile(t *testing.T) {
+	defer testUnsetCredentialsEnv(t)()
+
+	path := filepath.Join(os.TempDir(), "databrickscfg-does-not-exist")
+
+	creds, err := resolveCredentials("https://explicit.cloud.databricks.com", "explicit-token", "", path, true)
+	if err != nil {
+		t.Fatalf("a missing config file should be ignored when credentials are configured, got: %s", err)
+	}
+	if creds.Token != "explicit-token" {
+		t.Fatalf("unexpected credentials: %+v", creds)
+	}
+
+	if _, err := resolveCredentials("", "", "", path, true); err == nil {
+		t.Fatal("expected an error when no credentials are configured")
+	}
+
+	if _, err := resolveCredentials("", "", "staging", path, true); err == nil {
+		t.Fatal("expected an error for an explicit profile in a missing config file")
+	}
+}
+
+func TestWorkspaceCloud(t *testing.T) {
+	expected := map[string]string{
+		"https://dbc-a1b2c3d4-e5f6.cloud.databricks.com":     "aws",
+		"https://adb-1234567890123456.7.azuredatabricks.net": "azure",
+		"https://1234567890123456.7.gcp.databricks.com":      "gcp",
+		"https://databricks.example.com":                     "",
+	}
+
+	for host, cloud := range expected {
+		if v := workspaceCloud(host); v != cloud {
+			t.Fatalf("expected %s to run on %q, got %q", host, cloud, v)
+		}
+	}
+}
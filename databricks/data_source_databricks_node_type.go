@@ -0,0 +1,219 @@
+package databricks
+
+import (
+	"fmt"
+	"github.com/cattail/databricks-sdk-go/databricks"
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+	"log"
+	"sort"
+	"strings"
+)
+
+func dataSourceDatabricksNodeType() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceDatabricksNodeTypeRead,
+
+		Schema: map[string]*schema.Schema{
+			"min_memory_gb": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntAtLeast(0),
+			},
+			"min_cores": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntAtLeast(0),
+			},
+			"min_gpus": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntAtLeast(0),
+			},
+			"local_disk": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"category": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"zone": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"description": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"memory_mb": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"num_cores": {
+				Type:     schema.TypeFloat,
+				Computed: true,
+			},
+			"num_gpus": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func dataSourceDatabricksNodeTypeRead(d *schema.ResourceData, m interface{}) error {
+	client := m.(*databricksClient).ClusterApi
+
+	resp, _, err := client.ListNodeTypes(nil)
+	if err != nil {
+		return newAPIError(err)
+	}
+
+	filter := nodeTypeFilter{
+		MinMemoryGB: d.Get("min_memory_gb").(int),
+		MinCores:    d.Get("min_cores").(int),
+		MinGPUs:     d.Get("min_gpus").(int),
+		LocalDisk:   d.Get("local_disk").(bool),
+		Category:    d.Get("category").(string),
+		Zone:        d.Get("zone").(string),
+	}
+	logJSON("[DEBUG] Selecting node type", filter)
+
+	nodeType, err := selectNodeType(resp.NodeTypes, filter)
+	if err != nil {
+		return err
+	}
+	log.Printf("[DEBUG] Selected node type %s", nodeType.NodeTypeId)
+
+	d.SetId(nodeType.NodeTypeId)
+
+	err = d.Set("description", nodeType.Description)
+	if err != nil {
+		return err
+	}
+
+	err = d.Set("memory_mb", int(nodeType.MemoryMb))
+	if err != nil {
+		return err
+	}
+
+	err = d.Set("num_cores", float64(nodeType.NumCores))
+	if err != nil {
+		return err
+	}
+
+	return d.Set("num_gpus", int(nodeType.NumGpus))
+}
+
+type nodeTypeFilter struct {
+	MinMemoryGB int    `json:"min_memory_gb"`
+	MinCores    int    `json:"min_cores"`
+	MinGPUs     int    `json:"min_gpus"`
+	LocalDisk   bool   `json:"local_disk"`
+	Category    string `json:"category"`
+	Zone        string `json:"zone"`
+}
+
+// nodeTypeAvailable reports whether clusters of the workspace can use the node type. Deprecated and hidden
+// types are left out, as are types that are not available in the region of the workspace.
+func nodeTypeAvailable(nodeType databricks.ClustersNodeType) bool {
+	if nodeType.IsDeprecated || nodeType.IsHidden {
+		return false
+	}
+
+	if nodeType.NodeInfo != nil {
+		for _, status := range nodeType.NodeInfo.Status {
+			switch status {
+			case databricks.NOT_AVAILABLE_IN_REGION_ClustersCloudProviderNodeStatus,
+				databricks.NOT_ENABLED_ON_SUBSCRIPTION_ClustersCloudProviderNodeStatus:
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// nodeTypeSupportedInZone reports whether the node type can be launched in the given zone, e.g. us-west-2a.
+// Node types that do not report the zones they are available in are assumed to be available in all of them.
+func nodeTypeSupportedInZone(nodeType databricks.ClustersNodeType, zone string) bool {
+	if nodeType.NodeInfo == nil || len(nodeType.NodeInfo.AvailableZones) == 0 {
+		return true
+	}
+
+	for _, availableZone := range nodeType.NodeInfo.AvailableZones {
+		if strings.EqualFold(availableZone, zone) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func nodeTypeLocalDisks(nodeType databricks.ClustersNodeType) int32 {
+	if nodeType.NodeInstanceType == nil {
+		return 0
+	}
+	return nodeType.NodeInstanceType.LocalDisks
+}
+
+func (filter nodeTypeFilter) matches(nodeType databricks.ClustersNodeType) bool {
+	if !nodeTypeAvailable(nodeType) {
+		return false
+	}
+
+	if int(nodeType.MemoryMb) < filter.MinMemoryGB*1024 || nodeType.NumCores < float32(filter.MinCores) ||
+		int(nodeType.NumGpus) < filter.MinGPUs {
+		return false
+	}
+
+	if filter.LocalDisk && nodeTypeLocalDisks(nodeType) == 0 {
+		return false
+	}
+
+	if filter.Category != "" && !strings.EqualFold(nodeType.Category, filter.Category) {
+		return false
+	}
+
+	if filter.Zone != "" && !nodeTypeSupportedInZone(nodeType, filter.Zone) {
+		return false
+	}
+
+	return true
+}
+
+// selectNodeType returns the smallest node type matching the filter, the one with the least memory, then
+// cores, GPUs and local disks. The API does not return prices, so this stands in for the cheapest one.
+func selectNodeType(nodeTypes []databricks.ClustersNodeType, filter nodeTypeFilter) (databricks.ClustersNodeType, error) {
+	candidates := make([]databricks.ClustersNodeType, 0)
+	for _, nodeType := range nodeTypes {
+		if filter.matches(nodeType) {
+			candidates = append(candidates, nodeType)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return databricks.ClustersNodeType{}, fmt.Errorf("no node type matches the given filters")
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.MemoryMb != b.MemoryMb {
+			return a.MemoryMb < b.MemoryMb
+		}
+		if a.NumCores != b.NumCores {
+			return a.NumCores < b.NumCores
+		}
+		if a.NumGpus != b.NumGpus {
+			return a.NumGpus < b.NumGpus
+		}
+		if nodeTypeLocalDisks(a) != nodeTypeLocalDisks(b) {
+			return nodeTypeLocalDisks(a) < nodeTypeLocalDisks(b)
+		}
+		return a.NodeTypeId < b.NodeTypeId
+	})
+
+	return candidates[0], nil
+}
@@ -0,0 +1,86 @@
+package databricks
+
+import (
+	"encoding/json"
+	"github.com/cattail/databricks-sdk-go/databricks"
+	"testing"
+)
+
+// a trimmed down response of the list-node-types endpoint
+const testNodeTypesResponse = `{
+  "node_types": [
+    {"node_type_id": "r4.xlarge", "memory_mb": 31232, "num_cores": 4.0, "num_gpus": 0, "category": "Memory Optimized",
+     "node_instance_type": {"instance_type_id": "r4.xlarge", "local_disks": 0}},
+    {"node_type_id": "m4.large", "memory_mb": 8192, "num_cores": 2.0, "num_gpus": 0, "category": "General Purpose",
+     "is_deprecated": true},
+    {"node_type_id": "m5d.large", "memory_mb": 8192, "num_cores": 2.0, "num_gpus": 0, "category": "General Purpose",
+     "node_instance_type": {"instance_type_id": "m5d.large", "local_disks": 1, "local_disk_size_gb": 75},
+     "node_info": {"available_zones": ["us-west-2a"]}},
+    {"node_type_id": "m5.large", "memory_mb": 8192, "num_cores": 2.0, "num_gpus": 0, "category": "General Purpose",
+     "node_instance_type": {"instance_type_id": "m5.large", "local_disks": 0},
+     "node_info": {"available_zones": ["us-west-2a", "us-west-2b"]}},
+    {"node_type_id": "m5.xlarge", "memory_mb": 16384, "num_cores": 4.0, "num_gpus": 0, "category": "General Purpose",
+     "node_instance_type": {"instance_type_id": "m5.xlarge", "local_disks": 0},
+     "node_info": {"available_zones": ["us-west-2a", "us-west-2b", "us-west-2c"]}},
+    {"node_type_id": "c5.xlarge", "memory_mb": 8192, "num_cores": 4.0, "num_gpus": 0, "category": "Compute Optimized",
+     "node_instance_type": {"instance_type_id": "c5.xlarge", "local_disks": 0}},
+    {"node_type_id": "p3.2xlarge", "memory_mb": 62464, "num_cores": 8.0, "num_gpus": 1, "category": "GPU Accelerated",
+     "node_instance_type": {"instance_type_id": "p3.2xlarge", "local_disks": 0}},
+    {"node_type_id": "g4dn.xlarge", "memory_mb": 16384, "num_cores": 4.0, "num_gpus": 1, "category": "GPU Accelerated",
+     "node_instance_type": {"instance_type_id": "g4dn.xlarge", "local_disks": 1}, "node_info": {"status": ["NotAvailableInRegion"]}}
+  ]
+}`
+
+func testNodeTypes(t *testing.T) []databricks.ClustersNodeType {
+	var resp databricks.ClustersListNodeTypesResponse
+	if err := json.Unmarshal([]byte(testNodeTypesResponse), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp.NodeTypes
+}
+
+func TestDatabricksNodeType_select(t *testing.T) {
+	nodeTypes := testNodeTypes(t)
+
+	cases := []struct {
+		filter   nodeTypeFilter
+		expected string
+	}{
+		{nodeTypeFilter{}, "m5.large"},
+		{nodeTypeFilter{LocalDisk: true}, "m5d.large"},
+		{nodeTypeFilter{MinCores: 4}, "c5.xlarge"},
+		{nodeTypeFilter{MinMemoryGB: 16}, "m5.xlarge"},
+		{nodeTypeFilter{MinMemoryGB: 16, Category: "memory optimized"}, "r4.xlarge"},
+		{nodeTypeFilter{MinGPUs: 1}, "p3.2xlarge"},
+		{nodeTypeFilter{Category: "General Purpose", Zone: "us-west-2b"}, "m5.large"},
+		{nodeTypeFilter{LocalDisk: true, Zone: "US-WEST-2A"}, "m5d.large"},
+		{nodeTypeFilter{MinCores: 4, Zone: "us-west-2d"}, "c5.xlarge"},
+		{nodeTypeFilter{Category: "General Purpose", Zone: "us-west-2c"}, "m5.xlarge"},
+	}
+
+	for _, c := range cases {
+		nodeType, err := selectNodeType(nodeTypes, c.filter)
+		if err != nil {
+			t.Fatalf("%+v: %s", c.filter, err)
+		}
+		if nodeType.NodeTypeId != c.expected {
+			t.Fatalf("%+v: expected %s, got %s", c.filter, c.expected, nodeType.NodeTypeId)
+		}
+	}
+}
+
+func TestDatabricksNodeType_selectNoMatch(t *testing.T) {
+	nodeTypes := testNodeTypes(t)
+
+	if _, err := selectNodeType(nodeTypes, nodeTypeFilter{MinGPUs: 1, LocalDisk: true}); err == nil {
+		t.Fatal("node types not available in the region should not be selected")
+	}
+
+	if _, err := selectNodeType(nodeTypes, nodeTypeFilter{LocalDisk: true, Zone: "us-west-2d"}); err == nil {
+		t.Fatal("node types not available in the zone should not be selected")
+	}
+
+	if _, err := selectNodeType(nodeTypes, nodeTypeFilter{MinMemoryGB: 1024}); err == nil {
+		t.Fatal("no node type should match")
+	}
+}
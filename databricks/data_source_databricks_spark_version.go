@@ -0,0 +1,216 @@
+package databricks
+
+import (
+	"fmt"
+	"github.com/cattail/databricks-sdk-go/databricks"
+	"github.com/hashicorp/terraform/helper/schema"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func dataSourceDatabricksSparkVersion() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceDatabricksSparkVersionRead,
+
+		Schema: map[string]*schema.Schema{
+			"latest": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"long_term_support": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"ml": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"gpu": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"photon": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"scala": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"spark_version": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"name": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func dataSourceDatabricksSparkVersionRead(d *schema.ResourceData, m interface{}) error {
+	client := m.(*databricksClient).ClusterApi
+
+	resp, _, err := client.SparkVersions(nil)
+	if err != nil {
+		return newAPIError(err)
+	}
+
+	filter := sparkVersionFilter{
+		Latest:          d.Get("latest").(bool),
+		LongTermSupport: d.Get("long_term_support").(bool),
+		ML:              d.Get("ml").(bool),
+		GPU:             d.Get("gpu").(bool),
+		Photon:          d.Get("photon").(bool),
+		Scala:           d.Get("scala").(string),
+		SparkVersion:    d.Get("spark_version").(string),
+	}
+	logJSON("[DEBUG] Selecting Databricks runtime", filter)
+
+	version, err := selectSparkVersion(resp.Versions, filter)
+	if err != nil {
+		return err
+	}
+	log.Printf("[DEBUG] Selected Databricks runtime %s", version.Key)
+
+	d.SetId(version.Key)
+
+	return d.Set("name", version.Name)
+}
+
+type sparkVersionFilter struct {
+	Latest          bool   `json:"latest"`
+	LongTermSupport bool   `json:"long_term_support"`
+	ML              bool   `json:"ml"`
+	GPU             bool   `json:"gpu"`
+	Photon          bool   `json:"photon"`
+	Scala           string `json:"scala"`
+	SparkVersion    string `json:"spark_version"`
+}
+
+// sparkVersion is a Databricks runtime as described by its key, e.g. 7.3.x-gpu-ml-scala2.12, and its name,
+// e.g. "7.3 LTS ML (includes Apache Spark 3.0.1, GPU, Scala 2.12)".
+type sparkVersion struct {
+	databricks.ClustersSparkVersion
+	Major           int
+	Minor           int
+	Scala           string
+	SparkVersion    string
+	LongTermSupport bool
+	Beta            bool
+	ML              bool
+	GPU             bool
+	Photon          bool
+}
+
+var sparkVersionKeyRegexp = regexp.MustCompile(`^(\d+)\.(\d+)\.x-(.*-)?scala(\d+\.\d+)$`)
+
+var sparkVersionNameRegexp = regexp.MustCompile(`Apache Spark (\d+(\.\d+)*)`)
+
+// parseSparkVersion parses the key and name of a runtime. It returns false for keys it does not recognise
+// and for special purpose runtimes such as Genomics, which are never selected.
+func parseSparkVersion(version databricks.ClustersSparkVersion) (sparkVersion, bool) {
+	match := sparkVersionKeyRegexp.FindStringSubmatch(version.Key)
+	if match == nil {
+		return sparkVersion{}, false
+	}
+
+	result := sparkVersion{
+		ClustersSparkVersion: version,
+		Scala:                match[4],
+		LongTermSupport:      strings.Contains(version.Name, "LTS"),
+		Beta:                 strings.Contains(strings.ToLower(version.Name), "beta"),
+	}
+	result.Major, _ = strconv.Atoi(match[1])
+	result.Minor, _ = strconv.Atoi(match[2])
+
+	for _, flavor := range strings.Split(strings.TrimSuffix(match[3], "-"), "-") {
+		switch flavor {
+		case "", "cpu":
+		case "ml":
+			result.ML = true
+		case "gpu":
+			result.GPU = true
+		case "photon":
+			result.Photon = true
+		default:
+			return sparkVersion{}, false
+		}
+	}
+
+	if match := sparkVersionNameRegexp.FindStringSubmatch(version.Name); match != nil {
+		result.SparkVersion = match[1]
+	}
+
+	return result, true
+}
+
+// matches reports whether the runtime satisfies the filter. ML, GPU and Photon runtimes are only selected when
+// asked for, and beta runtimes never are.
+func (v sparkVersion) matches(filter sparkVersionFilter) bool {
+	if v.Beta || v.ML != filter.ML || v.GPU != filter.GPU || v.Photon != filter.Photon {
+		return false
+	}
+
+	if filter.LongTermSupport && !v.LongTermSupport {
+		return false
+	}
+
+	if filter.Scala != "" && v.Scala != filter.Scala {
+		return false
+	}
+
+	// 3.0 matches Spark 3.0 and 3.0.1, but not 3.00 or 3.01
+	if filter.SparkVersion != "" && v.SparkVersion != filter.SparkVersion &&
+		!strings.HasPrefix(v.SparkVersion, filter.SparkVersion+".") {
+		return false
+	}
+
+	return true
+}
+
+// selectSparkVersion returns the only runtime matching the filter, or the newest one when the filter asks
+// for the latest runtime.
+func selectSparkVersion(versions []databricks.ClustersSparkVersion, filter sparkVersionFilter) (databricks.ClustersSparkVersion, error) {
+	candidates := make([]sparkVersion, 0)
+	for _, version := range versions {
+		if parsed, ok := parseSparkVersion(version); ok && parsed.matches(filter) {
+			candidates = append(candidates, parsed)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return databricks.ClustersSparkVersion{}, fmt.Errorf("no Databricks runtime matches the given filters")
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Major != candidates[j].Major {
+			return candidates[i].Major < candidates[j].Major
+		}
+		if candidates[i].Minor != candidates[j].Minor {
+			return candidates[i].Minor < candidates[j].Minor
+		}
+		return candidates[i].Scala < candidates[j].Scala
+	})
+
+	if len(candidates) > 1 && !filter.Latest {
+		keys := make([]string, 0, len(candidates))
+		for _, candidate := range candidates {
+			keys = append(keys, candidate.Key)
+		}
+		return databricks.ClustersSparkVersion{}, fmt.Errorf(
+			"%d Databricks runtimes match the given filters (%s), narrow them down or set latest to true",
+			len(candidates), strings.Join(keys, ", "))
+	}
+
+	return candidates[len(candidates)-1].ClustersSparkVersion, nil
+}
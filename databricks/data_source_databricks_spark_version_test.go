@@ -0,0 +1,87 @@
+package databricks
+
+import (
+	"encoding/json"
+	"github.com/cattail/databricks-sdk-go/databricks"
+	"testing"
+)
+
+// a trimmed down response of the spark-versions endpoint
+const testSparkVersionsResponse = `{
+  "versions": [
+    {"key": "6.4.x-scala2.11", "name": "6.4 Extended Support (includes Apache Spark 2.4.5, Scala 2.11)"},
+    {"key": "6.4.x-cpu-ml-scala2.11", "name": "6.4 Extended Support ML (includes Apache Spark 2.4.5, Scala 2.11)"},
+    {"key": "7.3.x-scala2.12", "name": "7.3 LTS (includes Apache Spark 3.0.1, Scala 2.12)"},
+    {"key": "7.3.x-cpu-ml-scala2.12", "name": "7.3 LTS ML (includes Apache Spark 3.0.1, Scala 2.12)"},
+    {"key": "7.3.x-gpu-ml-scala2.12", "name": "7.3 LTS ML (includes Apache Spark 3.0.1, GPU, Scala 2.12)"},
+    {"key": "7.3.x-hls-scala2.12", "name": "7.3 LTS Genomics (includes Apache Spark 3.0.1, Scala 2.12)"},
+    {"key": "8.4.x-scala2.12", "name": "8.4 (includes Apache Spark 3.1.2, Scala 2.12)"},
+    {"key": "8.4.x-photon-scala2.12", "name": "8.4 Photon (includes Apache Spark 3.1.2, Scala 2.12)"},
+    {"key": "9.1.x-scala2.12", "name": "9.1 LTS (includes Apache Spark 3.1.2, Scala 2.12)"},
+    {"key": "9.1.x-cpu-ml-scala2.12", "name": "9.1 LTS ML (includes Apache Spark 3.1.2, Scala 2.12)"},
+    {"key": "9.1.x-photon-scala2.12", "name": "9.1 LTS Photon (includes Apache Spark 3.1.2, Scala 2.12)"},
+    {"key": "10.0.x-scala2.12", "name": "10.0 Beta (includes Apache Spark 3.2.0, Scala 2.12)"},
+    {"key": "apache-spark-2.4.x-scala2.11", "name": "Light 2.4 (includes Apache Spark 2.4, Scala 2.11)"}
+  ]
+}`
+
+func testSparkVersions(t *testing.T) []databricks.ClustersSparkVersion {
+	var resp databricks.ClustersSparkVersionsResponse
+	if err := json.Unmarshal([]byte(testSparkVersionsResponse), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp.Versions
+}
+
+func TestDatabricksSparkVersion_select(t *testing.T) {
+	versions := testSparkVersions(t)
+
+	cases := []struct {
+		filter   sparkVersionFilter
+		expected string
+	}{
+		{sparkVersionFilter{Latest: true}, "9.1.x-scala2.12"},
+		{sparkVersionFilter{Latest: true, ML: true}, "9.1.x-cpu-ml-scala2.12"},
+		{sparkVersionFilter{Latest: true, ML: true, GPU: true}, "7.3.x-gpu-ml-scala2.12"},
+		{sparkVersionFilter{Latest: true, Photon: true}, "9.1.x-photon-scala2.12"},
+		{sparkVersionFilter{Latest: true, Scala: "2.11"}, "6.4.x-scala2.11"},
+		{sparkVersionFilter{Latest: true, SparkVersion: "3.0"}, "7.3.x-scala2.12"},
+		{sparkVersionFilter{Latest: true, SparkVersion: "3.1.2"}, "9.1.x-scala2.12"},
+		{sparkVersionFilter{Latest: true, LongTermSupport: true, SparkVersion: "3"}, "9.1.x-scala2.12"},
+		{sparkVersionFilter{Photon: true, SparkVersion: "3.1", LongTermSupport: true}, "9.1.x-photon-scala2.12"},
+	}
+
+	for _, c := range cases {
+		version, err := selectSparkVersion(versions, c.filter)
+		if err != nil {
+			t.Fatalf("%+v: %s", c.filter, err)
+		}
+		if version.Key != c.expected {
+			t.Fatalf("%+v: expected %s, got %s", c.filter, c.expected, version.Key)
+		}
+	}
+}
+
+func TestDatabricksSparkVersion_selectNotExactlyOne(t *testing.T) {
+	versions := testSparkVersions(t)
+
+	// beta and Genomics runtimes are never selected
+	if _, err := selectSparkVersion(versions, sparkVersionFilter{Latest: true, SparkVersion: "3.2"}); err == nil {
+		t.Fatal("no runtime should match")
+	}
+
+	// versions are matched by component, Spark 3.1.2 is not a 3.10 version
+	if _, err := selectSparkVersion(versions, sparkVersionFilter{Latest: true, SparkVersion: "3.10"}); err == nil {
+		t.Fatal("no runtime should match")
+	}
+
+	if _, err := selectSparkVersion(versions, sparkVersionFilter{LongTermSupport: true}); err == nil {
+		t.Fatal("several runtimes match, so one should only be picked with latest")
+	}
+}
+
+func TestDatabricksSparkVersion_latestIsOptIn(t *testing.T) {
+	if dataSourceDatabricksSparkVersion().Schema["latest"].Default != false {
+		t.Fatal("filters matching several runtimes should fail unless latest is set")
+	}
+}
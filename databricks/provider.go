@@ -3,6 +3,10 @@ package databricks
 import (
 	"github.com/cattail/databricks-sdk-go/databricks"
 	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+	"log"
+	"net/http"
+	"time"
 )
 
 func Provider() *schema.Provider {
@@ -10,25 +14,124 @@ func Provider() *schema.Provider {
 		Schema: map[string]*schema.Schema{
 			"domain": {
 				Type:     schema.TypeString,
-				Required: true,
+				Optional: true,
 			},
 			"token": {
-				Type:     schema.TypeString,
-				Required: true,
+				Type:      schema.TypeString,
+				Optional:  true,
+				Sensitive: true,
+			},
+			"profile": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("DATABRICKS_CONFIG_PROFILE", nil),
+			},
+			"config_file": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("DATABRICKS_CONFIG_FILE", defaultConfigFile),
+			},
+			"azure_client_id": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("ARM_CLIENT_ID", nil),
+			},
+			"azure_client_secret": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Sensitive:   true,
+				DefaultFunc: schema.EnvDefaultFunc("ARM_CLIENT_SECRET", nil),
+			},
+			"azure_tenant_id": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("ARM_TENANT_ID", nil),
+			},
+			"azure_workspace_resource_id": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("DATABRICKS_AZURE_WORKSPACE_RESOURCE_ID", nil),
+			},
+			"max_retries": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      defaultMaxRetries,
+				ValidateFunc: validation.IntAtLeast(0),
+			},
+			"retry_timeout": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      defaultRetryTimeout,
+				ValidateFunc: validateDuration,
 			},
 		},
+		DataSourcesMap: map[string]*schema.Resource{
+			"databricks_node_type":     dataSourceDatabricksNodeType(),
+			"databricks_spark_version": dataSourceDatabricksSparkVersion(),
+		},
 		ResourcesMap: map[string]*schema.Resource{
-			"databricks_cluster": resourceDatabricksCluster(),
-			"databricks_job":     resourceDatabricksJob(),
+			"databricks_cluster":         resourceDatabricksCluster(),
+			"databricks_cluster_library": resourceDatabricksClusterLibrary(),
+			"databricks_cluster_policy":  resourceDatabricksClusterPolicy(),
+			"databricks_instance_pool":   resourceDatabricksInstancePool(),
+			"databricks_job":             resourceDatabricksJob(),
 		},
 		ConfigureFunc: providerConfigure,
 	}
 }
 
 func providerConfigure(d *schema.ResourceData) (interface{}, error) {
+	principal, err := newAzureServicePrincipal(
+		d.Get("azure_client_id").(string),
+		d.Get("azure_client_secret").(string),
+		d.Get("azure_tenant_id").(string),
+		d.Get("azure_workspace_resource_id").(string),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	creds, err := resolveCredentials(
+		d.Get("domain").(string),
+		d.Get("token").(string),
+		d.Get("profile").(string),
+		d.Get("config_file").(string),
+		principal == nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[INFO] Using Databricks host %s from %s", creds.Host, creds.HostSource)
+
+	retryTimeout, err := time.ParseDuration(d.Get("retry_timeout").(string))
+	if err != nil {
+		return nil, err
+	}
+
 	cfg := databricks.NewConfiguration()
-	cfg.AddDefaultHeader("Authorization", "Bearer "+d.Get("token").(string))
-	cfg.BasePath = d.Get("domain").(string) + "/api/2.0"
-	client := databricks.NewAPIClient(cfg)
+	var transport http.RoundTripper = http.DefaultTransport
+	if principal != nil {
+		log.Printf("[INFO] Using Azure AD service principal %s for workspace %s", principal.ClientID, principal.WorkspaceResourceID)
+		transport = newAzureAuthTransport(*principal, transport)
+	} else {
+		log.Printf("[INFO] Using Databricks token from %s", creds.TokenSource)
+		cfg.AddDefaultHeader("Authorization", "Bearer "+creds.Token)
+	}
+	cfg.HTTPClient = &http.Client{
+		Transport: newRetryTransport(transport, d.Get("max_retries").(int), retryTimeout),
+	}
+	cfg.BasePath = creds.Host + "/api/2.0"
+	client := &databricksClient{
+		APIClient: databricks.NewAPIClient(cfg),
+		Cloud:     workspaceCloud(creds.Host),
+	}
 	return client, nil
 }
+
+// databricksClient is the meta of the provider, passed to its resources and data sources.
+type databricksClient struct {
+	*databricks.APIClient
+
+	// Cloud is the cloud the workspace runs on, aws, azure or gcp, or empty when its host does not tell.
+	Cloud string
+}
@@ -28,8 +28,8 @@ func TestProvider_impl(t *testing.T) {
 }
 
 func testAccPreCheck(t *testing.T) {
-	if v := os.Getenv("DATABRICKS_DOMAIN"); v == "" {
-		t.Fatal("DATABRICKS_DOMAIN must be set for acceptance tests")
+	if v := os.Getenv("DATABRICKS_HOST"); v == "" {
+		t.Fatal("DATABRICKS_HOST must be set for acceptance tests")
 	}
 
 	if v := os.Getenv("DATABRICKS_TOKEN"); v == "" {
@@ -1,11 +1,14 @@
 package databricks
 
 import (
+	"context"
+	"fmt"
 	"github.com/cattail/databricks-sdk-go/databricks"
 	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/helper/validation"
 	"log"
-	"net/http"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -15,13 +18,25 @@ func resourceDatabricksCluster() *schema.Resource {
 		Read:   resourceDatabricksClusterRead,
 		Update: resourceDatabricksClusterUpdate,
 		Delete: resourceDatabricksClusterDelete,
+		Importer: &schema.ResourceImporter{
+			State: resourceDatabricksClusterImport,
+		},
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(30 * time.Minute),
+			Update: schema.DefaultTimeout(30 * time.Minute),
+			Delete: schema.DefaultTimeout(30 * time.Minute),
+		},
+
+		CustomizeDiff: resourceDatabricksClusterCustomizeDiff,
 
 		Schema: map[string]*schema.Schema{
 			"num_workers": {
 				Type:     schema.TypeInt,
 				Optional: true,
-				// FIXME(Chiyu): we can't reuse cluster resource schema in job if we define ConflictsWith here.
-				//ConflictsWith: []string{"autoscale"},
+				// ConflictsWith can't be used in a schema shared with the job new_cluster, see
+				// validateClusterWorkers
+				DiffSuppressFunc: suppressNumWorkersWithAutoscale,
 			},
 			"autoscale": {
 				Type:     schema.TypeList,
@@ -39,7 +54,10 @@ func resourceDatabricksCluster() *schema.Resource {
 						},
 					},
 				},
-				//ConflictsWith: []string{"num_workers"},
+			},
+			"single_node": {
+				Type:     schema.TypeBool,
+				Optional: true,
 			},
 			"cluster_name": {
 				Type:     schema.TypeString,
@@ -57,12 +75,15 @@ func resourceDatabricksCluster() *schema.Resource {
 			"aws_attributes": {
 				Type:     schema.TypeList,
 				Optional: true,
+				// Databricks fills in the zone and EBS settings when they are not configured
+				Computed: true,
 				MaxItems: 1,
 				Elem: &schema.Resource{
 					Schema: map[string]*schema.Schema{
 						"zone_id": {
 							Type:     schema.TypeString,
 							Optional: true,
+							Computed: true,
 						},
 						"instance_profile_arn": {
 							Type:     schema.TypeString,
@@ -71,6 +92,7 @@ func resourceDatabricksCluster() *schema.Resource {
 						"ebs_volume_type": {
 							Type:     schema.TypeString,
 							Optional: true,
+							Computed: true,
 							ValidateFunc: validation.StringInSlice([]string{
 								string(databricks.GENERAL_PURPOSE_SSD_ClustersEbsVolumeType),
 								string(databricks.THROUGHPUT_OPTIMIZED_HDD_ClustersEbsVolumeType),
@@ -79,21 +101,135 @@ func resourceDatabricksCluster() *schema.Resource {
 						"ebs_volume_count": {
 							Type:     schema.TypeInt,
 							Optional: true,
+							Computed: true,
 						},
 						"ebs_volume_size": {
 							Type:     schema.TypeInt,
 							Optional: true,
+							Computed: true,
+						},
+						"ebs_volume_iops": {
+							Type:         schema.TypeInt,
+							Optional:     true,
+							Computed:     true,
+							ValidateFunc: validation.IntBetween(3000, 16000),
+						},
+						"ebs_volume_throughput": {
+							Type:         schema.TypeInt,
+							Optional:     true,
+							Computed:     true,
+							ValidateFunc: validation.IntBetween(125, 1000),
+						},
+						"first_on_demand": {
+							Type:         schema.TypeInt,
+							Optional:     true,
+							Computed:     true,
+							ValidateFunc: validation.IntAtLeast(0),
+						},
+						"availability": {
+							Type:     schema.TypeString,
+							Optional: true,
+							Computed: true,
+							ValidateFunc: validation.StringInSlice([]string{
+								string(databricks.SPOT_ClustersAwsAvailability),
+								string(databricks.ON_DEMAND_ClustersAwsAvailability),
+								string(databricks.SPOT_WITH_FALLBACK_ClustersAwsAvailability),
+							}, false),
+						},
+						"spot_bid_price_percent": {
+							Type:         schema.TypeInt,
+							Optional:     true,
+							Computed:     true,
+							ValidateFunc: validation.IntBetween(1, 10000),
+						},
+					},
+				},
+			},
+			"azure_attributes": {
+				Type:     schema.TypeList,
+				Optional: true,
+				// read back with the availability, first_on_demand and spot_bid_max_price Databricks defaults to
+				Computed: true,
+				MaxItems: 1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"availability": {
+							Type:     schema.TypeString,
+							Optional: true,
+							Computed: true,
+							ValidateFunc: validation.StringInSlice([]string{
+								string(databricks.SPOT_AZURE_ClustersAzureAvailability),
+								string(databricks.ON_DEMAND_AZURE_ClustersAzureAvailability),
+								string(databricks.SPOT_WITH_FALLBACK_AZURE_ClustersAzureAvailability),
+							}, false),
+						},
+						"first_on_demand": {
+							Type:         schema.TypeInt,
+							Optional:     true,
+							Computed:     true,
+							ValidateFunc: validation.IntAtLeast(1),
+						},
+						"spot_bid_max_price": {
+							Type:         schema.TypeFloat,
+							Optional:     true,
+							Computed:     true,
+							ValidateFunc: validateSpotBidMaxPrice,
+						},
+					},
+				},
+			},
+			"gcp_attributes": {
+				Type:     schema.TypeList,
+				Optional: true,
+				Computed: true,
+				MaxItems: 1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"google_service_account": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+						"use_preemptible_executors": {
+							Type:     schema.TypeBool,
+							Optional: true,
+						},
+						"boot_disk_size": {
+							Type:         schema.TypeInt,
+							Optional:     true,
+							Computed:     true,
+							ValidateFunc: validation.IntAtLeast(1),
 						},
 					},
 				},
 			},
 			"node_type_id": {
 				Type:     schema.TypeString,
-				Required: true,
+				Optional: true,
+				// clusters in an instance pool use the node type of the pool
+				Computed: true,
+			},
+			"policy_id": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"apply_policy_default_values": {
+				Type:     schema.TypeBool,
+				Optional: true,
+			},
+			"instance_pool_id": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"driver_instance_pool_id": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				DiffSuppressFunc: suppressDefaultDriverInstancePool,
 			},
 			"driver_node_type_id": {
 				Type:     schema.TypeString,
 				Optional: true,
+				// defaults to node_type_id
+				Computed: true,
 			},
 			"ssh_public_keys": {
 				Type:     schema.TypeList,
@@ -148,6 +284,96 @@ func resourceDatabricksCluster() *schema.Resource {
 					},
 				},
 			},
+			"init_scripts": {
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"dbfs": {
+							Type:     schema.TypeList,
+							Optional: true,
+							MaxItems: 1,
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"destination": {
+										Type:     schema.TypeString,
+										Required: true,
+									},
+								},
+							},
+						},
+						"s3": {
+							Type:     schema.TypeList,
+							Optional: true,
+							MaxItems: 1,
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"destination": {
+										Type:     schema.TypeString,
+										Required: true,
+									},
+									"region": {
+										Type:     schema.TypeString,
+										Optional: true,
+									},
+									"endpoint": {
+										Type:     schema.TypeString,
+										Optional: true,
+									},
+									"enable_encryption": {
+										Type:     schema.TypeBool,
+										Optional: true,
+									},
+									"encryption_type": {
+										Type:         schema.TypeString,
+										Optional:     true,
+										ValidateFunc: validation.StringInSlice([]string{"sse-s3", "sse-kms"}, false),
+									},
+									"kms_key": {
+										Type:     schema.TypeString,
+										Optional: true,
+									},
+									"canned_acl": {
+										Type:     schema.TypeString,
+										Optional: true,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"docker_image": {
+				Type:     schema.TypeList,
+				Optional: true,
+				MaxItems: 1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"url": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"basic_auth": {
+							Type:     schema.TypeList,
+							Optional: true,
+							MaxItems: 1,
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"username": {
+										Type:     schema.TypeString,
+										Required: true,
+									},
+									"password": {
+										Type:      schema.TypeString,
+										Required:  true,
+										Sensitive: true,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
 			"spark_env_vars": {
 				Type:     schema.TypeMap,
 				Optional: true,
@@ -156,63 +382,414 @@ func resourceDatabricksCluster() *schema.Resource {
 			"autotermination_minutes": {
 				Type:     schema.TypeInt,
 				Optional: true,
+				Computed: true,
 			},
 			"enable_elastic_disk": {
 				Type:     schema.TypeBool,
 				Optional: true,
+				Computed: true,
+			},
+			"no_wait": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"permanently_delete": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  true,
+			},
+			"is_pinned": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				// clusters pinned in the UI stay pinned unless is_pinned is set
+				Computed: true,
+			},
+			"restart_on_update": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  true,
+			},
+			"pending_settings": {
+				Type: schema.TypeList,
+				// the settings staged while the cluster runs with restart_on_update = false
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"desired_state": {
+				Type:     schema.TypeString,
+				Optional: true,
+				// whether the cluster runs is left alone when this is not configured
+				Computed: true,
+				ValidateFunc: validation.StringInSlice([]string{
+					string(databricks.RUNNING_ClustersClusterState),
+					string(databricks.TERMINATED_ClustersClusterState),
+				}, false),
+			},
+			"state": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"state_message": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"driver": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"private_ip": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"public_dns": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"node_id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"instance_id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"host_private_ip": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"start_timestamp": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+					},
+				},
+			},
+			"spark_context_id": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"jdbc_port": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"default_tags": {
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"creator_user_name": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"start_time": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"termination_reason": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"code": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"parameters": {
+							Type:     schema.TypeMap,
+							Computed: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+					},
+				},
 			},
 		},
 	}
 }
 
+// resourceDatabricksClusterManagementFields control how databricks_cluster manages a cluster rather than
+// describe the cluster, so they are not part of the settings sent to the API.
+var resourceDatabricksClusterManagementFields = []string{
+	"no_wait",
+	"permanently_delete",
+	"restart_on_update",
+	"pending_settings",
+	"is_pinned",
+	"desired_state",
+}
+
+// resourceDatabricksClusterRuntimeFields are computed attributes describing a cluster while it exists.
+var resourceDatabricksClusterRuntimeFields = []string{
+	"state",
+	"state_message",
+	"driver",
+	"spark_context_id",
+	"jdbc_port",
+	"default_tags",
+	"creator_user_name",
+	"start_time",
+	"termination_reason",
+}
+
+// resourceDatabricksClusterSettingsSchema is the schema of the settings of a cluster, which the new_cluster
+// block of databricks_job shares.
+func resourceDatabricksClusterSettingsSchema() map[string]*schema.Schema {
+	result := resourceDatabricksCluster().Schema
+	for _, k := range resourceDatabricksClusterManagementFields {
+		delete(result, k)
+	}
+	for _, k := range resourceDatabricksClusterRuntimeFields {
+		delete(result, k)
+	}
+	return result
+}
+
 func resourceDatabricksClusterCreate(d *schema.ResourceData, m interface{}) error {
-	client := m.(*databricks.APIClient).ClusterApi
+	client := m.(*databricksClient).ClusterApi
 
 	request := getClusterSettings(d)
 	logJSON("[DEBUG] Creating cluster", request)
 
 	resp, _, err := client.CreateCluster(nil, request)
 	if err != nil {
-		return err
+		return clusterPolicyError(request.PolicyId, newAPIError(err))
 	}
 
 	d.SetId(resp.ClusterId)
 
+	// new clusters are always started, they only have to be terminated when that is the desired state
+	if d.Get("desired_state").(string) == string(databricks.TERMINATED_ClustersClusterState) {
+		err = resourceDatabricksClusterApplyDesiredState(client, resp.ClusterId,
+			databricks.TERMINATED_ClustersClusterState, d.Timeout(schema.TimeoutCreate))
+		if err != nil {
+			return err
+		}
+	} else if !d.Get("no_wait").(bool) {
+		_, err = waitClusterState(client, resp.ClusterId, []databricks.ClustersClusterState{
+			databricks.RUNNING_ClustersClusterState,
+		}, d.Timeout(schema.TimeoutCreate))
+		if err != nil {
+			return err
+		}
+	}
+
+	if d.Get("is_pinned").(bool) {
+		err = resourceDatabricksClusterApplyPinned(client, resp.ClusterId, true)
+		if err != nil {
+			return err
+		}
+	}
+
 	return resourceDatabricksClusterRead(d, m)
 }
 
 func resourceDatabricksClusterUpdate(d *schema.ResourceData, m interface{}) error {
-	client := m.(*databricks.APIClient).ClusterApi
+	client := m.(*databricksClient).ClusterApi
 
 	clusterId := d.Id()
 
-	waitClusterState(client, clusterId, []databricks.ClustersClusterState{
+	info, err := waitClusterState(client, clusterId, []databricks.ClustersClusterState{
 		databricks.RUNNING_ClustersClusterState,
 		databricks.TERMINATED_ClustersClusterState,
-	})
+	}, d.Timeout(schema.TimeoutUpdate))
+	if err != nil {
+		return err
+	}
 
-	request := getClusterSettings(d)
-	request.ClusterId = clusterId
-	logJSON("[DEBUG] Updating cluster", request)
+	running := info.State != nil && *info.State == databricks.RUNNING_ClustersClusterState
+
+	desiredState := databricks.ClustersClusterState(d.Get("desired_state").(string))
+	desiredStateChanged := desiredState != "" && d.HasChange("desired_state")
+
+	// a cluster terminated by the same apply is edited once it is terminated, rather than restarted first
+	if desiredStateChanged && desiredState == databricks.TERMINATED_ClustersClusterState {
+		err = resourceDatabricksClusterApplyDesiredState(client, clusterId, desiredState, d.Timeout(schema.TimeoutUpdate))
+		if err != nil {
+			return err
+		}
+		running = false
+	}
+
+	pending, err := resourceDatabricksClusterApplySettings(d, client, running)
+	if err != nil {
+		return err
+	}
 
-	_, err := client.EditCluster(nil, request)
+	err = d.Set("pending_settings", pending)
 	if err != nil {
 		return err
 	}
 
+	if desiredStateChanged && desiredState != databricks.TERMINATED_ClustersClusterState {
+		err = resourceDatabricksClusterApplyDesiredState(client, clusterId, desiredState, d.Timeout(schema.TimeoutUpdate))
+		if err != nil {
+			return err
+		}
+	}
+
+	if d.HasChange("is_pinned") {
+		err = resourceDatabricksClusterApplyPinned(client, clusterId, d.Get("is_pinned").(bool))
+		if err != nil {
+			return err
+		}
+	}
+
 	return resourceDatabricksClusterRead(d, m)
 }
 
-func resourceDatabricksClusterDelete(d *schema.ResourceData, m interface{}) error {
-	client := m.(*databricks.APIClient).ClusterApi
+// clusterSizeSettings can be changed on a running cluster by resizing it, without a restart.
+var clusterSizeSettings = []string{"num_workers", "autoscale"}
+
+// clusterSettingsUpdate is how the changed settings of a cluster are applied.
+type clusterSettingsUpdate struct {
+	// Edit sends all the settings, which restarts a running cluster.
+	Edit bool
+	// Resize changes the size of a running cluster without restarting it.
+	Resize bool
+	// Deferred are the changed settings staged while the cluster runs.
+	Deferred []string
+}
 
-	log.Printf("[DEBUG] Deleting cluster: %s", d.Id())
+// resourceDatabricksClusterPlanUpdate decides how to apply the changed settings. Editing a running cluster
+// restarts it, so a running cluster whose size is the only change is resized instead, and other changes are
+// deferred while it runs when restart_on_update is false.
+func resourceDatabricksClusterPlanUpdate(changed []string, running, restartOnUpdate bool) clusterSettingsUpdate {
+	sizeChanged := false
+	other := make([]string, 0)
+	for _, k := range changed {
+		if find(toSliceInterface(clusterSizeSettings), k) {
+			sizeChanged = true
+		} else {
+			other = append(other, k)
+		}
+	}
 
-	_, err := client.PermanentDeleteCluster(nil, databricks.ClustersPermanentDeleteRequest{
-		ClusterId: d.Id(),
-	})
+	if !running {
+		return clusterSettingsUpdate{Edit: len(changed) > 0}
+	}
+
+	if len(other) > 0 && restartOnUpdate {
+		return clusterSettingsUpdate{Edit: true}
+	}
+
+	if len(other) > 0 {
+		return clusterSettingsUpdate{Resize: sizeChanged, Deferred: other}
+	}
+
+	return clusterSettingsUpdate{Resize: sizeChanged}
+}
+
+// resourceDatabricksClusterApplySettings sends the changed settings of the cluster, along with the ones staged
+// by earlier applies, and returns the settings that are staged until the cluster is terminated.
+func resourceDatabricksClusterApplySettings(d *schema.ResourceData, client *databricks.ClusterApiService, running bool) ([]string, error) {
+	// the plan only marks pending_settings as unknown when it applies them, its old value lists them
+	pending, _ := d.GetChange("pending_settings")
+	changed := resourceDatabricksClusterStagedSettings(resourceDatabricksClusterChangedSettings(d), toSliceString(pending))
+	if len(changed) == 0 {
+		log.Printf("[DEBUG] No settings of cluster %s changed, not editing it", d.Id())
+		return nil, nil
+	}
+
+	request := getClusterSettings(d)
+	request.ClusterId = d.Id()
+
+	update := resourceDatabricksClusterPlanUpdate(changed, running, d.Get("restart_on_update").(bool))
+
+	if update.Edit {
+		logJSON("[DEBUG] Updating cluster", request)
+
+		_, err := client.EditCluster(nil, request)
+		if err != nil {
+			return nil, clusterPolicyError(request.PolicyId, newAPIError(err))
+		}
+	}
+
+	if update.Resize {
+		err := resourceDatabricksClusterResize(d, client, request)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return update.Deferred, nil
+}
+
+// resourceDatabricksClusterStagedSettings adds the settings staged by earlier applies to the changed ones.
+func resourceDatabricksClusterStagedSettings(changed, pending []string) []string {
+	result := append([]string{}, changed...)
+	for _, k := range pending {
+		if !find(toSliceInterface(result), k) {
+			result = append(result, k)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+func resourceDatabricksClusterResize(d *schema.ResourceData, client *databricks.ClusterApiService, clusterSettings databricks.NewCluster) error {
+	request := databricks.ClustersResizeRequest{
+		ClusterId:  d.Id(),
+		NumWorkers: clusterSettings.NumWorkers,
+		Autoscale:  clusterSettings.Autoscale,
+	}
+	logJSON("[DEBUG] Resizing cluster", request)
+
+	_, err := client.ResizeCluster(nil, request)
 	if err != nil {
-		return err
+		return clusterPolicyError(clusterSettings.PolicyId, newAPIError(err))
+	}
+
+	_, err = waitClusterState(client, d.Id(), []databricks.ClustersClusterState{
+		databricks.RUNNING_ClustersClusterState,
+	}, d.Timeout(schema.TimeoutUpdate))
+
+	return err
+}
+
+// resourceDatabricksClusterChangedSettings lists the changed attributes that are sent to the API, leaving
+// out the ones that only control how the provider manages the cluster.
+func resourceDatabricksClusterChangedSettings(d interface {
+	HasChange(key string) bool
+}) []string {
+	changed := make([]string, 0)
+	for k := range resourceDatabricksClusterSettingsSchema() {
+		if d.HasChange(k) {
+			changed = append(changed, k)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+func resourceDatabricksClusterDelete(d *schema.ResourceData, m interface{}) error {
+	client := m.(*databricksClient).ClusterApi
+
+	if d.Get("permanently_delete").(bool) {
+		log.Printf("[DEBUG] Deleting cluster: %s", d.Id())
+
+		_, err := client.PermanentDeleteCluster(nil, databricks.ClustersPermanentDeleteRequest{
+			ClusterId: d.Id(),
+		})
+		if err != nil {
+			return newAPIError(err)
+		}
+	} else {
+		log.Printf("[DEBUG] Terminating cluster: %s", d.Id())
+
+		_, err := client.DeleteCluster(nil, databricks.ClustersDeleteRequest{
+			ClusterId: d.Id(),
+		})
+		if err != nil {
+			return newAPIError(err)
+		}
+
+		_, err = waitClusterState(client, d.Id(), []databricks.ClustersClusterState{
+			databricks.TERMINATED_ClustersClusterState,
+		}, d.Timeout(schema.TimeoutDelete))
+		if err != nil {
+			return err
+		}
 	}
 
 	d.SetId("")
@@ -220,12 +797,28 @@ func resourceDatabricksClusterDelete(d *schema.ResourceData, m interface{}) erro
 	return nil
 }
 
+func resourceDatabricksClusterImport(d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+	// fields that only control how the provider manages the cluster can't be read back, so they start
+	// out with their defaults
+	clusterSchema := resourceDatabricksCluster().Schema
+	for _, k := range resourceDatabricksClusterManagementFields {
+		if v := clusterSchema[k].Default; v != nil {
+			if err := d.Set(k, v); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return []*schema.ResourceData{d}, nil
+}
+
 func resourceDatabricksClusterRead(d *schema.ResourceData, m interface{}) error {
-	client := m.(*databricks.APIClient).ClusterApi
+	client := m.(*databricksClient).ClusterApi
 
-	resp, httpResponse, err := client.GetCluster(nil, d.Id())
+	resp, _, err := client.GetCluster(nil, d.Id())
 	if err != nil {
-		if resourceDatabricksClusterNotExistsError(httpResponse) {
+		err = newAPIError(err)
+		if isNotFoundError(err) {
 			log.Printf("[WARN] Cluster (%s) not found, removing from state", d.Id())
 			d.SetId("")
 			return nil
@@ -238,11 +831,391 @@ func resourceDatabricksClusterRead(d *schema.ResourceData, m interface{}) error
 		return err
 	}
 
-	return setClusterSettings(d, *clusterSettings)
+	// staged settings are not on the cluster yet, so they keep their configured values until they are applied
+	staged := make(map[string]interface{})
+	for _, k := range toSliceString(d.Get("pending_settings")) {
+		staged[k] = d.Get(k)
+	}
+
+	err = setClusterSettings(d, *clusterSettings)
+	if err != nil {
+		return err
+	}
+
+	for k, v := range staged {
+		err = d.Set(k, v)
+		if err != nil {
+			return err
+		}
+	}
+
+	pinned, err := resourceDatabricksClusterIsPinned(client, d.Id())
+	if err != nil {
+		return err
+	}
+
+	err = d.Set("is_pinned", pinned)
+	if err != nil {
+		return err
+	}
+
+	return setClusterRuntimeAttributes(d, resp)
+}
+
+// resourceDatabricksClusterIsPinned looks the cluster up in the cluster list, the only place reporting
+// who pinned it.
+func resourceDatabricksClusterIsPinned(client *databricks.ClusterApiService, clusterId string) (bool, error) {
+	resp, _, err := client.ListClusters(nil)
+	if err != nil {
+		return false, newAPIError(err)
+	}
+
+	for _, cluster := range resp.Clusters {
+		if cluster.ClusterId == clusterId {
+			return cluster.PinnedByUserName != "", nil
+		}
+	}
+
+	return false, nil
+}
+
+func resourceDatabricksClusterApplyPinned(client *databricks.ClusterApiService, clusterId string, pinned bool) error {
+	var err error
+
+	if pinned {
+		log.Printf("[DEBUG] Pinning cluster: %s", clusterId)
+		_, err = client.PinCluster(nil, databricks.ClustersPinRequest{
+			ClusterId: clusterId,
+		})
+	} else {
+		log.Printf("[DEBUG] Unpinning cluster: %s", clusterId)
+		_, err = client.UnpinCluster(nil, databricks.ClustersUnpinRequest{
+			ClusterId: clusterId,
+		})
+	}
+
+	if err != nil {
+		return newAPIError(err)
+	}
+
+	return nil
+}
+
+// resourceDatabricksClusterDesiredState maps the current state of a cluster to the desired state it is
+// heading to. ERROR and UNKNOWN are kept as they are, so they show up as drift.
+func resourceDatabricksClusterDesiredState(state databricks.ClustersClusterState) string {
+	switch state {
+	case databricks.PENDING_ClustersClusterState,
+		databricks.RUNNING_ClustersClusterState,
+		databricks.RESTARTING_ClustersClusterState,
+		databricks.RESIZING_ClustersClusterState:
+		return string(databricks.RUNNING_ClustersClusterState)
+	case databricks.TERMINATING_ClustersClusterState,
+		databricks.TERMINATED_ClustersClusterState:
+		return string(databricks.TERMINATED_ClustersClusterState)
+	}
+	return string(state)
+}
+
+// resourceDatabricksClusterApplyDesiredState starts or terminates the cluster once it has settled, so that
+// it ends up in desiredState.
+func resourceDatabricksClusterApplyDesiredState(client *databricks.ClusterApiService, clusterId string, desiredState databricks.ClustersClusterState, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	info, err := waitClusterState(client, clusterId, []databricks.ClustersClusterState{
+		databricks.RUNNING_ClustersClusterState,
+		databricks.TERMINATED_ClustersClusterState,
+	}, timeout)
+	if err != nil {
+		return err
+	}
+
+	if *info.State == desiredState {
+		return nil
+	}
+
+	switch desiredState {
+	case databricks.RUNNING_ClustersClusterState:
+		log.Printf("[DEBUG] Starting cluster: %s", clusterId)
+		_, err = client.StartCluster(nil, databricks.ClustersStartRequest{
+			ClusterId: clusterId,
+		})
+	case databricks.TERMINATED_ClustersClusterState:
+		log.Printf("[DEBUG] Terminating cluster: %s", clusterId)
+		_, err = client.DeleteCluster(nil, databricks.ClustersDeleteRequest{
+			ClusterId: clusterId,
+		})
+	default:
+		return fmt.Errorf("unsupported desired state %s", desiredState)
+	}
+	if err != nil {
+		return newAPIError(err)
+	}
+
+	_, err = waitClusterState(client, clusterId, []databricks.ClustersClusterState{desiredState}, time.Until(deadline))
+	return err
+}
+
+func resourceDatabricksClusterExpandInitScripts(initScripts []interface{}) []databricks.ClustersInitScriptInfo {
+	result := make([]databricks.ClustersInitScriptInfo, len(initScripts))
+
+	for i, value := range initScripts {
+		m := value.(map[string]interface{})
+		initScript := databricks.ClustersInitScriptInfo{}
+
+		if v, ok := getOk(m, "dbfs"); ok {
+			clustersInitScriptInfoDbfs := databricks.ClustersInitScriptInfoDbfs{}
+			clustersInitScriptInfoDbfsElem := v.([]interface{})[0].(map[string]interface{})
+			if v, ok := clustersInitScriptInfoDbfsElem["destination"]; ok {
+				clustersInitScriptInfoDbfs.Destination = v.(string)
+			}
+			initScript.Dbfs = &clustersInitScriptInfoDbfs
+		}
+
+		if v, ok := getOk(m, "s3"); ok {
+			clustersInitScriptInfoS3 := databricks.ClustersInitScriptInfoS3{}
+			clustersInitScriptInfoS3Elem := v.([]interface{})[0].(map[string]interface{})
+			if v, ok := clustersInitScriptInfoS3Elem["destination"]; ok {
+				clustersInitScriptInfoS3.Destination = v.(string)
+			}
+			if v, ok := clustersInitScriptInfoS3Elem["region"]; ok {
+				clustersInitScriptInfoS3.Region = v.(string)
+			}
+			if v, ok := clustersInitScriptInfoS3Elem["endpoint"]; ok {
+				clustersInitScriptInfoS3.Endpoint = v.(string)
+			}
+			if v, ok := clustersInitScriptInfoS3Elem["enable_encryption"]; ok {
+				clustersInitScriptInfoS3.EnableEncryption = v.(bool)
+			}
+			if v, ok := clustersInitScriptInfoS3Elem["encryption_type"]; ok {
+				clustersInitScriptInfoS3.EncryptionType = v.(string)
+			}
+			if v, ok := clustersInitScriptInfoS3Elem["kms_key"]; ok {
+				clustersInitScriptInfoS3.KmsKey = v.(string)
+			}
+			if v, ok := clustersInitScriptInfoS3Elem["canned_acl"]; ok {
+				clustersInitScriptInfoS3.CannedAcl = v.(string)
+			}
+			initScript.S3 = &clustersInitScriptInfoS3
+		}
+
+		result[i] = initScript
+	}
+
+	return result
+}
+
+func resourceDatabricksClusterFlattenInitScripts(initScripts []databricks.ClustersInitScriptInfo) []map[string]interface{} {
+	result := make([]map[string]interface{}, len(initScripts))
+
+	for i, initScript := range initScripts {
+		attrs := make(map[string]interface{})
+
+		if initScript.Dbfs != nil {
+			innerAttrs := make(map[string]interface{})
+			innerAttrs["destination"] = initScript.Dbfs.Destination
+			attrs["dbfs"] = []interface{}{innerAttrs}
+		}
+
+		if initScript.S3 != nil {
+			innerAttrs := make(map[string]interface{})
+			innerAttrs["destination"] = initScript.S3.Destination
+			innerAttrs["region"] = initScript.S3.Region
+			innerAttrs["endpoint"] = initScript.S3.Endpoint
+			innerAttrs["enable_encryption"] = initScript.S3.EnableEncryption
+			innerAttrs["encryption_type"] = initScript.S3.EncryptionType
+			innerAttrs["kms_key"] = initScript.S3.KmsKey
+			innerAttrs["canned_acl"] = initScript.S3.CannedAcl
+			attrs["s3"] = []interface{}{innerAttrs}
+		}
+
+		result[i] = attrs
+	}
+
+	return result
+}
+
+func resourceDatabricksClusterExpandDockerImage(dockerImage []interface{}) databricks.ClustersDockerImage {
+	m := dockerImage[0].(map[string]interface{})
+
+	result := databricks.ClustersDockerImage{}
+
+	if v, ok := getOk(m, "url"); ok {
+		result.Url = v.(string)
+	}
+
+	if v, ok := getOk(m, "basic_auth"); ok {
+		clustersDockerBasicAuth := databricks.ClustersDockerBasicAuth{}
+		clustersDockerBasicAuthElem := v.([]interface{})[0].(map[string]interface{})
+		if v, ok := clustersDockerBasicAuthElem["username"]; ok {
+			clustersDockerBasicAuth.Username = v.(string)
+		}
+		if v, ok := clustersDockerBasicAuthElem["password"]; ok {
+			clustersDockerBasicAuth.Password = v.(string)
+		}
+		result.BasicAuth = &clustersDockerBasicAuth
+	}
+
+	return result
+}
+
+// resourceDatabricksClusterFlattenDockerImage flattens the docker image of a cluster. The API does not
+// return the registry password, so the password currently in state is kept when it is missing.
+func resourceDatabricksClusterFlattenDockerImage(dockerImage *databricks.ClustersDockerImage, current interface{}) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0)
+	if dockerImage != nil {
+		attrs := make(map[string]interface{})
+		attrs["url"] = dockerImage.Url
+
+		if dockerImage.BasicAuth != nil {
+			innerAttrs := make(map[string]interface{})
+			innerAttrs["username"] = dockerImage.BasicAuth.Username
+			innerAttrs["password"] = dockerImage.BasicAuth.Password
+			if innerAttrs["password"] == "" {
+				innerAttrs["password"] = resourceDatabricksClusterDockerPassword(current)
+			}
+			attrs["basic_auth"] = []interface{}{innerAttrs}
+		}
+
+		result = append(result, attrs)
+	}
+
+	return result
+}
+
+// resourceDatabricksClusterDockerPassword returns the docker_image password in state, which the API never
+// returns.
+func resourceDatabricksClusterDockerPassword(current interface{}) string {
+	l, ok := current.([]interface{})
+	if !ok || len(l) == 0 || l[0] == nil {
+		return ""
+	}
+
+	basicAuth, ok := l[0].(map[string]interface{})["basic_auth"].([]interface{})
+	if !ok || len(basicAuth) == 0 || basicAuth[0] == nil {
+		return ""
+	}
+
+	password, _ := basicAuth[0].(map[string]interface{})["password"].(string)
+	return password
+}
+
+// clusterManagedSparkConf, clusterManagedCustomTags and clusterManagedSparkEnvVars are keys Databricks adds
+// to clusters on its own. They are only read back when they are configured, so that they don't show as
+// changes on every plan.
+var clusterManagedSparkConf = []string{
+	"spark.databricks.delta.preview.enabled",
+	"spark.databricks.repl.allowedLanguages",
+}
+
+var clusterManagedCustomTags = []string{
+	"ResourceClass",
+}
+
+var clusterManagedSparkEnvVars = []string{
+	"PYSPARK_PYTHON",
+}
+
+// resourceDatabricksClusterFilterManagedKeys removes the managed keys from values unless they are in
+// configured, the map currently in state. Any other key is kept, so that real changes are still detected.
+func resourceDatabricksClusterFilterManagedKeys(values map[string]string, configured interface{}, managed []string) map[string]string {
+	result := make(map[string]string)
+
+	for k, v := range values {
+		if find(toSliceInterface(managed), k) && !hasMapKey(configured, k) {
+			log.Printf("[DEBUG] Ignoring %s=%s added by Databricks", k, v)
+			continue
+		}
+		result[k] = v
+	}
+
+	return result
+}
+
+// clusterSingleNodeSparkConf and clusterSingleNodeCustomTags turn a cluster without workers into a single
+// node cluster, running Spark on the driver only.
+var clusterSingleNodeSparkConf = map[string]string{
+	"spark.databricks.cluster.profile": "singleNode",
+	"spark.master":                     "local[*]",
+}
+
+var clusterSingleNodeCustomTags = map[string]string{
+	"ResourceClass": "SingleNode",
+}
+
+func resourceDatabricksClusterApplySingleNode(clusterSettings *databricks.NewCluster) {
+	clusterSettings.NumWorkers = 0
+	clusterSettings.Autoscale = nil
+
+	sparkConf := make(map[string]string)
+	for k, v := range clusterSettings.SparkConf {
+		sparkConf[k] = v
+	}
+	for k, v := range clusterSingleNodeSparkConf {
+		sparkConf[k] = v
+	}
+	clusterSettings.SparkConf = sparkConf
+
+	customTags := make(map[string]string)
+	for k, v := range clusterSettings.CustomTags {
+		customTags[k] = v
+	}
+	for k, v := range clusterSingleNodeCustomTags {
+		customTags[k] = v
+	}
+	clusterSettings.CustomTags = customTags
+}
+
+// resourceDatabricksClusterStripSingleNode tells whether the cluster is a single node cluster, and if so
+// removes the settings added by resourceDatabricksClusterApplySingleNode so that they don't show as changes.
+func resourceDatabricksClusterStripSingleNode(clusterSettings *databricks.NewCluster) bool {
+	if clusterSettings.SparkConf["spark.databricks.cluster.profile"] != clusterSingleNodeSparkConf["spark.databricks.cluster.profile"] {
+		return false
+	}
+
+	sparkConf := make(map[string]string)
+	for k, v := range clusterSettings.SparkConf {
+		if _, ok := clusterSingleNodeSparkConf[k]; !ok {
+			sparkConf[k] = v
+		}
+	}
+	clusterSettings.SparkConf = sparkConf
+
+	customTags := make(map[string]string)
+	for k, v := range clusterSettings.CustomTags {
+		if _, ok := clusterSingleNodeCustomTags[k]; !ok {
+			customTags[k] = v
+		}
+	}
+	clusterSettings.CustomTags = customTags
+
+	return true
+}
+
+func resourceDatabricksClusterFlattenDriver(driver *databricks.ClustersSparkNode) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0)
+	if driver != nil {
+		result = append(result, map[string]interface{}{
+			"private_ip":      driver.PrivateIp,
+			"public_dns":      driver.PublicDns,
+			"node_id":         driver.NodeId,
+			"instance_id":     driver.InstanceId,
+			"host_private_ip": driver.HostPrivateIp,
+			"start_timestamp": int(driver.StartTimestamp),
+		})
+	}
+	return result
 }
 
-func resourceDatabricksClusterNotExistsError(httpResponse *http.Response) bool {
-	return httpResponse.StatusCode >= 400
+func resourceDatabricksClusterFlattenTerminationReason(reason *databricks.ClustersTerminationReason) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0)
+	if reason != nil && reason.Code != nil {
+		result = append(result, map[string]interface{}{
+			"code":       string(*reason.Code),
+			"parameters": reason.Parameters,
+		})
+	}
+	return result
 }
 
 func resourceDatabricksClusterExpandAutoscale(autoscale []interface{}) databricks.ClustersAutoScale {
@@ -291,6 +1264,27 @@ func resourceDatabricksClusterExpandAwsAttributes(awsAttributes []interface{}) d
 		result.EbsVolumeSize = int32(v.(int))
 	}
 
+	if v, ok := getOk(m, "ebs_volume_iops"); ok {
+		result.EbsVolumeIops = int32(v.(int))
+	}
+
+	if v, ok := getOk(m, "ebs_volume_throughput"); ok {
+		result.EbsVolumeThroughput = int32(v.(int))
+	}
+
+	if v, ok := getOk(m, "first_on_demand"); ok {
+		result.FirstOnDemand = int32(v.(int))
+	}
+
+	if v, ok := getOk(m, "availability"); ok {
+		availability := databricks.ClustersAwsAvailability(v.(string))
+		result.Availability = &availability
+	}
+
+	if v, ok := getOk(m, "spot_bid_price_percent"); ok {
+		result.SpotBidPricePercent = int32(v.(int))
+	}
+
 	return result
 }
 
@@ -302,9 +1296,87 @@ func resourceDatabricksClusterFlattenAwsAttributes(awsAttributes *databricks.Clu
 		attrs["instance_profile_arn"] = awsAttributes.InstanceProfileArn
 		if awsAttributes.EbsVolumeType != nil {
 			attrs["ebs_volume_type"] = string(*awsAttributes.EbsVolumeType)
-			attrs["ebs_volume_count"] = int(awsAttributes.EbsVolumeCount)
-			attrs["ebs_volume_size"] = int(awsAttributes.EbsVolumeSize)
 		}
+		attrs["ebs_volume_count"] = int(awsAttributes.EbsVolumeCount)
+		attrs["ebs_volume_size"] = int(awsAttributes.EbsVolumeSize)
+		attrs["ebs_volume_iops"] = int(awsAttributes.EbsVolumeIops)
+		attrs["ebs_volume_throughput"] = int(awsAttributes.EbsVolumeThroughput)
+		attrs["first_on_demand"] = int(awsAttributes.FirstOnDemand)
+		if awsAttributes.Availability != nil {
+			attrs["availability"] = string(*awsAttributes.Availability)
+		}
+		attrs["spot_bid_price_percent"] = int(awsAttributes.SpotBidPricePercent)
+
+		result = append(result, attrs)
+	}
+
+	return result
+}
+
+func resourceDatabricksClusterExpandAzureAttributes(azureAttributes []interface{}) databricks.ClustersAzureAttributes {
+	m := azureAttributes[0].(map[string]interface{})
+
+	result := databricks.ClustersAzureAttributes{}
+
+	if v, ok := getOk(m, "availability"); ok {
+		availability := databricks.ClustersAzureAvailability(v.(string))
+		result.Availability = &availability
+	}
+
+	if v, ok := getOk(m, "first_on_demand"); ok {
+		result.FirstOnDemand = int32(v.(int))
+	}
+
+	if v, ok := getOk(m, "spot_bid_max_price"); ok {
+		result.SpotBidMaxPrice = v.(float64)
+	}
+
+	return result
+}
+
+func resourceDatabricksClusterFlattenAzureAttributes(azureAttributes *databricks.ClustersAzureAttributes) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0)
+	if azureAttributes != nil {
+		attrs := make(map[string]interface{})
+		if azureAttributes.Availability != nil {
+			attrs["availability"] = string(*azureAttributes.Availability)
+		}
+		attrs["first_on_demand"] = int(azureAttributes.FirstOnDemand)
+		attrs["spot_bid_max_price"] = azureAttributes.SpotBidMaxPrice
+
+		result = append(result, attrs)
+	}
+
+	return result
+}
+
+func resourceDatabricksClusterExpandGcpAttributes(gcpAttributes []interface{}) databricks.ClustersGcpAttributes {
+	m := gcpAttributes[0].(map[string]interface{})
+
+	result := databricks.ClustersGcpAttributes{}
+
+	if v, ok := getOk(m, "google_service_account"); ok {
+		result.GoogleServiceAccount = v.(string)
+	}
+
+	if v, ok := getOk(m, "use_preemptible_executors"); ok {
+		result.UsePreemptibleExecutors = v.(bool)
+	}
+
+	if v, ok := getOk(m, "boot_disk_size"); ok {
+		result.BootDiskSize = int32(v.(int))
+	}
+
+	return result
+}
+
+func resourceDatabricksClusterFlattenGcpAttributes(gcpAttributes *databricks.ClustersGcpAttributes) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0)
+	if gcpAttributes != nil {
+		attrs := make(map[string]interface{})
+		attrs["google_service_account"] = gcpAttributes.GoogleServiceAccount
+		attrs["use_preemptible_executors"] = gcpAttributes.UsePreemptibleExecutors
+		attrs["boot_disk_size"] = int(gcpAttributes.BootDiskSize)
 
 		result = append(result, attrs)
 	}
@@ -312,6 +1384,204 @@ func resourceDatabricksClusterFlattenAwsAttributes(awsAttributes *databricks.Clu
 	return result
 }
 
+// validateSpotBidMaxPrice accepts a price in US dollars, or -1 to bid up to the on-demand price.
+func validateSpotBidMaxPrice(v interface{}, k string) (ws []string, errors []error) {
+	if price := v.(float64); price != -1 && price <= 0 {
+		errors = append(errors, fmt.Errorf("%q must be a positive price, or -1 to bid up to the on-demand price, got %v", k, price))
+	}
+	return
+}
+
+// clusterCloudAttributes are the blocks holding the settings specific to each cloud. A workspace only
+// accepts the block of the cloud it runs on.
+var clusterCloudAttributes = []string{"aws_attributes", "azure_attributes", "gcp_attributes"}
+
+func resourceDatabricksClusterCustomizeDiff(d *schema.ResourceDiff, m interface{}) error {
+	// settings staged while the cluster ran are applied once it is found terminated
+	if d.Get("state").(string) == string(databricks.TERMINATED_ClustersClusterState) &&
+		len(d.Get("pending_settings").([]interface{})) > 0 {
+		err := d.SetNewComputed("pending_settings")
+		if err != nil {
+			return err
+		}
+	}
+
+	return validateClusterSettings(d, "", workspaceCloudOf(m))
+}
+
+// workspaceCloudOf returns the cloud of the workspace the provider talks to, or "" when it is not known.
+func workspaceCloudOf(m interface{}) string {
+	if client, ok := m.(*databricksClient); ok {
+		return client.Cloud
+	}
+	return ""
+}
+
+// clusterSettingOk is like GetOk, but also reports a setting as set when its value is only known at apply
+// time, e.g. instance_pool_id = "${databricks_instance_pool.pool.id}" on the first plan.
+func clusterSettingOk(d *schema.ResourceDiff, key string) (interface{}, bool) {
+	v, ok := d.GetOk(key)
+	return v, ok || !d.NewValueKnown(key)
+}
+
+// validateClusterSettings runs the plan-time checks that span several cluster settings, for both clusters
+// and job clusters. prefix locates the cluster settings, e.g. "new_cluster.0." in a job, and cloud is the
+// cloud of the workspace, if known.
+func validateClusterSettings(d *schema.ResourceDiff, prefix, cloud string) error {
+	err := validateClusterNodeType(d, prefix)
+	if err != nil {
+		return err
+	}
+
+	err = validateClusterWorkers(d, prefix)
+	if err != nil {
+		return err
+	}
+
+	err = validateClusterCloudAttributes(d, prefix, cloud)
+	if err != nil {
+		return err
+	}
+
+	return validateClusterAwsAttributes(d, prefix)
+}
+
+// validateClusterWorkers checks that a single node cluster has no workers, that num_workers and autoscale
+// are not both set, and that autoscale has a valid range. num_workers = 0 is a valid size, which GetOk can't
+// tell apart from an unset num_workers, so leaving both unset is not an error.
+func validateClusterWorkers(d *schema.ResourceDiff, prefix string) error {
+	numWorkersKey := prefix + "num_workers"
+	_, numWorkers := clusterSettingOk(d, numWorkersKey)
+	autoscale, hasAutoscale := clusterSettingOk(d, prefix+"autoscale")
+
+	if v, ok := d.GetOk(prefix + "single_node"); ok && v.(bool) {
+		if hasAutoscale {
+			return fmt.Errorf("%sautoscale can't be set on a single node cluster", prefix)
+		}
+
+		if numWorkers {
+			return fmt.Errorf("%snum_workers can't be set on a single node cluster", prefix)
+		}
+
+		return nil
+	}
+
+	if !hasAutoscale {
+		return nil
+	}
+
+	// next to autoscale, num_workers keeps the value of the state when it is not configured, e.g. the size
+	// of a cluster that is moved to autoscale, so it only conflicts when the plan sets it
+	if numWorkers && d.HasChange(numWorkersKey) {
+		return fmt.Errorf("only one of %snum_workers or %sautoscale can be set", prefix, prefix)
+	}
+
+	// autoscale is nil while it is not known yet
+	l, _ := autoscale.([]interface{})
+	if len(l) > 0 && l[0] != nil {
+		m := l[0].(map[string]interface{})
+		// max_workers is 0 while it is not known yet
+		if m["max_workers"].(int) > 0 && m["min_workers"].(int) > m["max_workers"].(int) {
+			return fmt.Errorf("%sautoscale.0.min_workers (%d) can't be greater than max_workers (%d)",
+				prefix, m["min_workers"].(int), m["max_workers"].(int))
+		}
+	}
+
+	return nil
+}
+
+// suppressNumWorkersWithAutoscale ignores an unset num_workers on autoscaling clusters, where the API reports
+// the current number of workers, so that a configured one still shows up and conflicts with autoscale. It
+// works for both clusters and job clusters by looking up autoscale next to the num_workers key, e.g.
+// new_cluster.0.autoscale.
+func suppressNumWorkersWithAutoscale(k, old, new string, d *schema.ResourceData) bool {
+	if new != "" && new != "0" {
+		return false
+	}
+
+	prefix := strings.TrimSuffix(k, "num_workers")
+	_, ok := d.GetOk(prefix + "autoscale")
+	return ok
+}
+
+// suppressDefaultDriverInstancePool ignores a driver pool that is not configured while it is the one the API
+// defaults it to, the pool of the workers. Once instance_pool_id changes, the driver pool read back from the
+// API is dropped from the plan along with it. d.Get still returns the pool of the state when instance_pool_id
+// is unset, but the driver pool is then not sent, and the next read clears it.
+func suppressDefaultDriverInstancePool(k, old, new string, d *schema.ResourceData) bool {
+	prefix := strings.TrimSuffix(k, "driver_instance_pool_id")
+	return new == "" && old == d.Get(prefix+"instance_pool_id").(string)
+}
+
+// validateClusterNodeType checks that the cluster either has a node type or takes it from an instance pool.
+func validateClusterNodeType(d *schema.ResourceDiff, prefix string) error {
+	_, nodeType := clusterSettingOk(d, prefix+"node_type_id")
+	_, pool := clusterSettingOk(d, prefix+"instance_pool_id")
+	driverPoolKey := prefix + "driver_instance_pool_id"
+	driverPoolId, driverPool := d.GetOk(driverPoolKey)
+
+	// a driver pool that is the previous pool of the workers was defaulted to it by the API rather than
+	// configured, see suppressDefaultDriverInstancePool
+	oldPoolId, _ := d.GetChange(prefix + "instance_pool_id")
+	if driverPool && !d.HasChange(driverPoolKey) && driverPoolId == oldPoolId {
+		driverPool = false
+	}
+
+	if !nodeType && !pool {
+		return fmt.Errorf("one of %snode_type_id or %sinstance_pool_id must be set", prefix, prefix)
+	}
+
+	if driverPool && !pool {
+		return fmt.Errorf("%sdriver_instance_pool_id requires %sinstance_pool_id", prefix, prefix)
+	}
+
+	return nil
+}
+
+// validateClusterAwsAttributes checks that the IOPS and throughput of gp3 volumes are set together, as the
+// API requires. Both are computed, so the check only runs when the plan changes one of them: values the API
+// filled in on its own are not configured and must not fail every later plan.
+func validateClusterAwsAttributes(d *schema.ResourceDiff, prefix string) error {
+	iopsKey := prefix + "aws_attributes.0.ebs_volume_iops"
+	throughputKey := prefix + "aws_attributes.0.ebs_volume_throughput"
+
+	if !d.HasChange(iopsKey) && !d.HasChange(throughputKey) {
+		return nil
+	}
+
+	_, iops := clusterSettingOk(d, iopsKey)
+	_, throughput := clusterSettingOk(d, throughputKey)
+
+	if iops != throughput {
+		return fmt.Errorf("%s and %s must be set together", iopsKey, throughputKey)
+	}
+
+	return nil
+}
+
+// validateClusterCloudAttributes fails the plan when the blocks of more than one cloud are set, or the block
+// of another cloud than the one of the workspace, rather than letting the API reject the cluster. The cloud
+// of workspaces behind a custom domain is not known, so only the first check applies to them.
+func validateClusterCloudAttributes(d *schema.ResourceDiff, prefix, cloud string) error {
+	configured := make([]string, 0)
+	for _, k := range clusterCloudAttributes {
+		if _, ok := d.GetOk(prefix + k); ok {
+			configured = append(configured, prefix+k)
+		}
+	}
+
+	if len(configured) > 1 {
+		return fmt.Errorf("only one of %s can be set, as a workspace runs on a single cloud", strings.Join(configured, ", "))
+	}
+
+	if len(configured) == 1 && cloud != "" && configured[0] != prefix+cloud+"_attributes" {
+		return fmt.Errorf("%s can't be set, as the workspace runs on %s: use %s%s_attributes instead",
+			configured[0], cloud, prefix, cloud)
+	}
+
+	return nil
+}
+
 func resourceDatabricksClusterExpandClusterLogConf(clusterLogConf []interface{}) databricks.ClustersClusterLogConf {
 	m := clusterLogConf[0].(map[string]interface{})
 
@@ -369,34 +1639,106 @@ func resourceDatabricksClusterFlattenClusterLogConf(clusterLogConf *databricks.C
 	return result
 }
 
-func waitClusterState(client *databricks.ClusterApiService, clusterId string, states []databricks.ClustersClusterState) {
-	res, _, _ := client.GetCluster(nil, clusterId)
+// clusterTransitionalStates are the states a cluster leaves on its own, e.g. while starting or resizing.
+var clusterTransitionalStates = []databricks.ClustersClusterState{
+	databricks.PENDING_ClustersClusterState,
+	databricks.RESTARTING_ClustersClusterState,
+	databricks.RESIZING_ClustersClusterState,
+	databricks.TERMINATING_ClustersClusterState,
+}
+
+// waitClusterState polls the cluster until it reaches one of the given states. It fails as soon as the
+// cluster is in any other state that is not transitional, such as ERROR or UNKNOWN, or once timeout passes.
+func waitClusterState(client *databricks.ClusterApiService, clusterId string, states []databricks.ClustersClusterState, timeout time.Duration) (*databricks.ClustersClusterInfo, error) {
+	target := make([]string, len(states))
+	for i, state := range states {
+		target[i] = string(state)
+	}
+
+	pending := make([]string, 0)
+	for _, state := range clusterTransitionalStates {
+		if !find(toSliceInterface(target), string(state)) {
+			pending = append(pending, string(state))
+		}
+	}
+
+	last, err := waitForState(pending, target, timeout, func(ctx context.Context) (interface{}, string, error) {
+		resp, _, err := client.GetCluster(ctx, clusterId)
+		if err != nil {
+			return nil, "", newAPIError(err)
+		}
+
+		state := databricks.UNKNOWN_ClustersClusterState
+		if resp.State != nil {
+			state = *resp.State
+		}
+		log.Printf("[DEBUG] Waiting for cluster %s to enter %s state from %s", clusterId, states, state)
+
+		return resp, string(state), nil
+	})
+
+	info, _ := last.(databricks.ClustersClusterInfo)
+	if err != nil {
+		return nil, clusterStateError(clusterId, info, err)
+	}
+
+	return &info, nil
+}
+
+// clusterStateError explains why a cluster did not reach the expected state using its state message and
+// termination reason.
+func clusterStateError(clusterId string, info databricks.ClustersClusterInfo, err error) error {
+	details := make([]string, 0)
+
+	if info.StateMessage != "" {
+		details = append(details, fmt.Sprintf("state message: %s", info.StateMessage))
+	}
 
-	newStates := make([]interface{}, len(states))
-	for i := range states {
-		newStates[i] = states[i]
+	if reason := info.TerminationReason; reason != nil && reason.Code != nil {
+		termination := fmt.Sprintf("termination reason: %s", *reason.Code)
+		if len(reason.Parameters) > 0 {
+			termination += fmt.Sprintf(" %v", reason.Parameters)
+		}
+		details = append(details, termination)
 	}
 
-	for !find(newStates, *res.State) {
-		res, _, _ = client.GetCluster(nil, clusterId)
-		time.Sleep(5 * time.Second)
-		log.Printf("[DEBUG] Waiting cluster enter %s state from %s\n", states, *res.State)
+	if len(details) == 0 {
+		return fmt.Errorf("error waiting for cluster %s: %s", clusterId, err)
 	}
+
+	return fmt.Errorf("error waiting for cluster %s: %s (%s)", clusterId, err, strings.Join(details, ", "))
 }
 
 func getClusterSettings(d interface{}) databricks.NewCluster {
 	clusterSettings := databricks.NewCluster{
 		SparkVersion: get(d, "spark_version").(string),
-		NodeTypeId:   get(d, "node_type_id").(string),
 	}
 
-	if v, ok := getOk(d, "num_workers"); ok {
-		clusterSettings.NumWorkers = int32(v.(int))
+	// the node types of a pooled cluster come from its pools, the ones in state were only read back
+	if v, ok := getOk(d, "instance_pool_id"); ok {
+		clusterSettings.InstancePoolId = v.(string)
+
+		if v, ok := getOk(d, "driver_instance_pool_id"); ok {
+			clusterSettings.DriverInstancePoolId = v.(string)
+		}
+	} else {
+		clusterSettings.NodeTypeId = get(d, "node_type_id").(string)
+	}
+
+	if v, ok := getOk(d, "policy_id"); ok {
+		clusterSettings.PolicyId = v.(string)
+	}
+
+	if v, ok := getOk(d, "apply_policy_default_values"); ok {
+		clusterSettings.ApplyPolicyDefaultValues = v.(bool)
 	}
 
+	// num_workers may still hold the size of the state next to autoscale, see validateClusterWorkers
 	if v, ok := getOk(d, "autoscale"); ok {
 		autoscale := resourceDatabricksClusterExpandAutoscale(v.([]interface{}))
 		clusterSettings.Autoscale = &autoscale
+	} else if v, ok := getOk(d, "num_workers"); ok {
+		clusterSettings.NumWorkers = int32(v.(int))
 	}
 
 	if v, ok := getOk(d, "cluster_name"); ok {
@@ -412,7 +1754,17 @@ func getClusterSettings(d interface{}) databricks.NewCluster {
 		clusterSettings.AwsAttributes = &awsAttributes
 	}
 
-	if v, ok := getOk(d, "driver_node_type_id"); ok {
+	if v, ok := getOk(d, "azure_attributes"); ok {
+		azureAttributes := resourceDatabricksClusterExpandAzureAttributes(v.([]interface{}))
+		clusterSettings.AzureAttributes = &azureAttributes
+	}
+
+	if v, ok := getOk(d, "gcp_attributes"); ok {
+		gcpAttributes := resourceDatabricksClusterExpandGcpAttributes(v.([]interface{}))
+		clusterSettings.GcpAttributes = &gcpAttributes
+	}
+
+	if v, ok := getOk(d, "driver_node_type_id"); ok && clusterSettings.InstancePoolId == "" {
 		clusterSettings.DriverNodeTypeId = v.(string)
 	}
 
@@ -429,6 +1781,15 @@ func getClusterSettings(d interface{}) databricks.NewCluster {
 		clusterSettings.ClusterLogConf = &clusterLogConf
 	}
 
+	if v, ok := getOk(d, "init_scripts"); ok {
+		clusterSettings.InitScripts = resourceDatabricksClusterExpandInitScripts(v.([]interface{}))
+	}
+
+	if v, ok := getOk(d, "docker_image"); ok {
+		dockerImage := resourceDatabricksClusterExpandDockerImage(v.([]interface{}))
+		clusterSettings.DockerImage = &dockerImage
+	}
+
 	if v, ok := getOk(d, "spark_env_vars"); ok {
 		clusterSettings.SparkEnvVars = toMapString(v)
 	}
@@ -441,11 +1802,85 @@ func getClusterSettings(d interface{}) databricks.NewCluster {
 		clusterSettings.EnableElasticDisk = v.(bool)
 	}
 
+	if v, ok := getOk(d, "single_node"); ok && v.(bool) {
+		resourceDatabricksClusterApplySingleNode(&clusterSettings)
+	}
+
 	return clusterSettings
 }
 
+// setClusterRuntimeAttributes sets the computed attributes that describe the running cluster rather than
+// its settings.
+func setClusterRuntimeAttributes(d *schema.ResourceData, clusterInfo databricks.ClustersClusterInfo) error {
+	if clusterInfo.State != nil {
+		err := d.Set("state", string(*clusterInfo.State))
+		if err != nil {
+			return err
+		}
+
+		err = d.Set("desired_state", resourceDatabricksClusterDesiredState(*clusterInfo.State))
+		if err != nil {
+			return err
+		}
+	}
+
+	err := d.Set("state_message", clusterInfo.StateMessage)
+	if err != nil {
+		return err
+	}
+
+	err = d.Set("driver", resourceDatabricksClusterFlattenDriver(clusterInfo.Driver))
+	if err != nil {
+		return err
+	}
+
+	err = d.Set("spark_context_id", int(clusterInfo.SparkContextId))
+	if err != nil {
+		return err
+	}
+
+	err = d.Set("jdbc_port", int(clusterInfo.JdbcPort))
+	if err != nil {
+		return err
+	}
+
+	err = d.Set("default_tags", clusterInfo.DefaultTags)
+	if err != nil {
+		return err
+	}
+
+	err = d.Set("creator_user_name", clusterInfo.CreatorUserName)
+	if err != nil {
+		return err
+	}
+
+	err = d.Set("start_time", int(clusterInfo.StartTime))
+	if err != nil {
+		return err
+	}
+
+	err = d.Set("termination_reason", resourceDatabricksClusterFlattenTerminationReason(clusterInfo.TerminationReason))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func setClusterSettings(d interface{}, clusterSettings databricks.NewCluster) error {
-	err := set(d, "spark_version", clusterSettings.SparkVersion)
+	// the single node settings are only hidden on clusters managed as single node ones, when written by hand
+	// in spark_conf and custom_tags they are read back like any other key
+	singleNode := false
+	if v, ok := getOk(d, "single_node"); ok && v.(bool) {
+		singleNode = resourceDatabricksClusterStripSingleNode(&clusterSettings)
+	}
+
+	err := set(d, "single_node", singleNode)
+	if err != nil {
+		return err
+	}
+
+	err = set(d, "spark_version", clusterSettings.SparkVersion)
 	if err != nil {
 		return err
 	}
@@ -455,7 +1890,13 @@ func setClusterSettings(d interface{}, clusterSettings databricks.NewCluster) er
 		return err
 	}
 
-	err = set(d, "num_workers", clusterSettings.NumWorkers)
+	// autoscaling clusters report their current number of workers, which is not a setting
+	numWorkers := clusterSettings.NumWorkers
+	if clusterSettings.Autoscale != nil {
+		numWorkers = 0
+	}
+
+	err = set(d, "num_workers", numWorkers)
 	if err != nil {
 		return err
 	}
@@ -470,7 +1911,7 @@ func setClusterSettings(d interface{}, clusterSettings databricks.NewCluster) er
 		return err
 	}
 
-	err = set(d, "spark_conf", clusterSettings.SparkConf)
+	err = set(d, "spark_conf", resourceDatabricksClusterFilterManagedKeys(clusterSettings.SparkConf, get(d, "spark_conf"), clusterManagedSparkConf))
 	if err != nil {
 		return err
 	}
@@ -480,17 +1921,43 @@ func setClusterSettings(d interface{}, clusterSettings databricks.NewCluster) er
 		return err
 	}
 
+	err = set(d, "azure_attributes", resourceDatabricksClusterFlattenAzureAttributes(clusterSettings.AzureAttributes))
+	if err != nil {
+		return err
+	}
+
+	err = set(d, "gcp_attributes", resourceDatabricksClusterFlattenGcpAttributes(clusterSettings.GcpAttributes))
+	if err != nil {
+		return err
+	}
+
 	err = set(d, "driver_node_type_id", clusterSettings.DriverNodeTypeId)
 	if err != nil {
 		return err
 	}
 
+	err = set(d, "instance_pool_id", clusterSettings.InstancePoolId)
+	if err != nil {
+		return err
+	}
+
+	// apply_policy_default_values is not returned by the API, so it is left as configured
+	err = set(d, "policy_id", clusterSettings.PolicyId)
+	if err != nil {
+		return err
+	}
+
+	err = set(d, "driver_instance_pool_id", clusterSettings.DriverInstancePoolId)
+	if err != nil {
+		return err
+	}
+
 	err = set(d, "ssh_public_keys", clusterSettings.SshPublicKeys)
 	if err != nil {
 		return err
 	}
 
-	err = set(d, "custom_tags", clusterSettings.CustomTags)
+	err = set(d, "custom_tags", resourceDatabricksClusterFilterManagedKeys(clusterSettings.CustomTags, get(d, "custom_tags"), clusterManagedCustomTags))
 	if err != nil {
 		return err
 	}
@@ -500,7 +1967,17 @@ func setClusterSettings(d interface{}, clusterSettings databricks.NewCluster) er
 		return err
 	}
 
-	err = set(d, "spark_env_vars", clusterSettings.SparkEnvVars)
+	err = set(d, "init_scripts", resourceDatabricksClusterFlattenInitScripts(clusterSettings.InitScripts))
+	if err != nil {
+		return err
+	}
+
+	err = set(d, "docker_image", resourceDatabricksClusterFlattenDockerImage(clusterSettings.DockerImage, get(d, "docker_image")))
+	if err != nil {
+		return err
+	}
+
+	err = set(d, "spark_env_vars", resourceDatabricksClusterFilterManagedKeys(clusterSettings.SparkEnvVars, get(d, "spark_env_vars"), clusterManagedSparkEnvVars))
 	if err != nil {
 		return err
 	}
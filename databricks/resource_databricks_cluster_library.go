@@ -0,0 +1,335 @@
+package databricks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/cattail/databricks-sdk-go/databricks"
+	"github.com/hashicorp/terraform/helper/schema"
+	"log"
+	"strings"
+	"time"
+)
+
+func resourceDatabricksClusterLibrary() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceDatabricksClusterLibraryCreate,
+		Read:   resourceDatabricksClusterLibraryRead,
+		Update: resourceDatabricksClusterLibraryUpdate,
+		Delete: resourceDatabricksClusterLibraryDelete,
+		Importer: &schema.ResourceImporter{
+			State: resourceDatabricksClusterLibraryImport,
+		},
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(30 * time.Minute),
+			Update: schema.DefaultTimeout(30 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"cluster_id": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"library": {
+				Type:     schema.TypeList,
+				Required: true,
+				Elem: &schema.Resource{
+					Schema: resourceDatabricksLibrarySchema(),
+				},
+			},
+		},
+	}
+}
+
+func resourceDatabricksClusterLibraryCreate(d *schema.ResourceData, m interface{}) error {
+	clusterId := d.Get("cluster_id").(string)
+	libraries := resourceDatabricksJobExpandLibraries(d.Get("library").([]interface{}))
+
+	err := installClusterLibraries(m.(*databricksClient).APIClient, clusterId, libraries, d.Timeout(schema.TimeoutCreate))
+	if err != nil {
+		return err
+	}
+
+	d.SetId(clusterId)
+
+	return resourceDatabricksClusterLibraryRead(d, m)
+}
+
+func resourceDatabricksClusterLibraryUpdate(d *schema.ResourceData, m interface{}) error {
+	client := m.(*databricksClient).APIClient
+
+	o, n := d.GetChange("library")
+	install, uninstall := diffLibraries(
+		resourceDatabricksJobExpandLibraries(o.([]interface{})),
+		resourceDatabricksJobExpandLibraries(n.([]interface{})))
+
+	err := uninstallClusterLibraries(client, d.Id(), uninstall)
+	if err != nil {
+		return err
+	}
+
+	err = installClusterLibraries(client, d.Id(), install, d.Timeout(schema.TimeoutUpdate))
+	if err != nil {
+		return err
+	}
+
+	return resourceDatabricksClusterLibraryRead(d, m)
+}
+
+func resourceDatabricksClusterLibraryDelete(d *schema.ResourceData, m interface{}) error {
+	libraries := resourceDatabricksJobExpandLibraries(d.Get("library").([]interface{}))
+
+	err := uninstallClusterLibraries(m.(*databricksClient).APIClient, d.Id(), libraries)
+	if err != nil {
+		return err
+	}
+
+	d.SetId("")
+
+	return nil
+}
+
+func resourceDatabricksClusterLibraryRead(d *schema.ResourceData, m interface{}) error {
+	installed, err := installedClusterLibraries(m.(*databricksClient).LibrariesApi, d.Id())
+	if err != nil {
+		if isNotFoundError(err) {
+			log.Printf("[WARN] Cluster (%s) not found, removing its libraries from state", d.Id())
+			d.SetId("")
+			return nil
+		}
+		return err
+	}
+
+	current := resourceDatabricksJobExpandLibraries(d.Get("library").([]interface{}))
+
+	err = d.Set("cluster_id", d.Id())
+	if err != nil {
+		return err
+	}
+
+	return d.Set("library", resourceDatabricksJobFlattenLibraries(managedLibraries(installed, current)))
+}
+
+// resourceDatabricksClusterLibraryImport adopts all the libraries installed on the cluster, as nothing is
+// managed yet. Read only keeps the managed ones afterwards.
+func resourceDatabricksClusterLibraryImport(d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+	installed, err := installedClusterLibraries(m.(*databricksClient).LibrariesApi, d.Id())
+	if err != nil {
+		return nil, err
+	}
+
+	err = d.Set("library", resourceDatabricksJobFlattenLibraries(installed))
+	if err != nil {
+		return nil, err
+	}
+
+	return []*schema.ResourceData{d}, nil
+}
+
+// installedClusterLibraries returns the libraries installed on the cluster by itself.
+func installedClusterLibraries(client *databricks.LibrariesApiService, clusterId string) ([]databricks.Library, error) {
+	resp, _, err := client.ClusterStatus(nil, clusterId)
+	if err != nil {
+		return nil, newAPIError(err)
+	}
+
+	installed := make([]databricks.Library, 0)
+	for _, status := range resp.LibraryStatuses {
+		// libraries installed on all clusters are managed in the workspace, and uninstalled ones are only
+		// waiting for the cluster to restart
+		if status.Library == nil || status.IsLibraryForAllClusters ||
+			(status.Status != nil && *status.Status == databricks.UNINSTALL_ON_RESTART_LibrariesLibraryInstallStatus) {
+			continue
+		}
+		installed = append(installed, *status.Library)
+	}
+
+	return installed, nil
+}
+
+func installClusterLibraries(client *databricks.APIClient, clusterId string, libraries []databricks.Library, timeout time.Duration) error {
+	if len(libraries) == 0 {
+		return nil
+	}
+
+	request := databricks.LibrariesInstallLibrariesRequest{
+		ClusterId: clusterId,
+		Libraries: libraries,
+	}
+	logJSON("[DEBUG] Installing libraries", request)
+
+	_, err := client.LibrariesApi.InstallLibraries(nil, request)
+	if err != nil {
+		return newAPIError(err)
+	}
+
+	// libraries are only installed while the cluster runs, a terminated cluster installs them once started
+	cluster, _, err := client.ClusterApi.GetCluster(nil, clusterId)
+	if err != nil {
+		return newAPIError(err)
+	}
+
+	if cluster.State == nil || *cluster.State != databricks.RUNNING_ClustersClusterState {
+		log.Printf("[INFO] Cluster %s is not running, not waiting for its libraries to be installed", clusterId)
+		return nil
+	}
+
+	return waitLibrariesInstalled(client.LibrariesApi, clusterId, libraries, timeout)
+}
+
+func uninstallClusterLibraries(client *databricks.APIClient, clusterId string, libraries []databricks.Library) error {
+	if len(libraries) == 0 {
+		return nil
+	}
+
+	request := databricks.LibrariesUninstallLibrariesRequest{
+		ClusterId: clusterId,
+		Libraries: libraries,
+	}
+	logJSON("[DEBUG] Uninstalling libraries", request)
+
+	_, err := client.LibrariesApi.UninstallLibraries(nil, request)
+	if err != nil {
+		err = newAPIError(err)
+		if isNotFoundError(err) {
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
+// waitLibrariesInstalled polls the cluster until all of the given libraries are installed. It fails as soon
+// as any of them failed or was skipped, with the messages reported for it.
+func waitLibrariesInstalled(client *databricks.LibrariesApiService, clusterId string, libraries []databricks.Library, timeout time.Duration) error {
+	pending := make(map[string]bool)
+	for _, library := range libraries {
+		pending[libraryKey(library)] = true
+	}
+
+	last, err := waitForState([]string{"INSTALLING"}, []string{"INSTALLED"}, timeout, func(ctx context.Context) (interface{}, string, error) {
+		resp, _, err := client.ClusterStatus(ctx, clusterId)
+		if err != nil {
+			return nil, "", newAPIError(err)
+		}
+
+		statuses := make([]databricks.LibrariesLibraryFullStatus, 0)
+		for _, status := range resp.LibraryStatuses {
+			if status.Library != nil && pending[libraryKey(*status.Library)] {
+				statuses = append(statuses, status)
+			}
+		}
+
+		state := librariesInstallState(statuses, len(pending))
+		log.Printf("[DEBUG] Waiting for libraries on cluster %s to be installed, currently %s", clusterId, state)
+
+		return statuses, state, nil
+	})
+
+	if err != nil {
+		statuses, _ := last.([]databricks.LibrariesLibraryFullStatus)
+		return librariesInstallError(clusterId, statuses, err)
+	}
+
+	return nil
+}
+
+// librariesInstallState summarizes the statuses of the libraries being installed as INSTALLED once all of
+// them are, FAILED if any of them failed or was skipped and INSTALLING otherwise.
+func librariesInstallState(statuses []databricks.LibrariesLibraryFullStatus, expected int) string {
+	installed := 0
+	for _, status := range statuses {
+		if status.Status == nil {
+			continue
+		}
+
+		switch *status.Status {
+		case databricks.FAILED_LibrariesLibraryInstallStatus, databricks.SKIPPED_LibrariesLibraryInstallStatus:
+			return "FAILED"
+		case databricks.INSTALLED_LibrariesLibraryInstallStatus:
+			installed++
+		}
+	}
+
+	if installed >= expected {
+		return "INSTALLED"
+	}
+
+	return "INSTALLING"
+}
+
+// librariesInstallError lists the libraries that did not get installed along with their messages.
+func librariesInstallError(clusterId string, statuses []databricks.LibrariesLibraryFullStatus, err error) error {
+	details := make([]string, 0)
+
+	for _, status := range statuses {
+		if status.Status == nil || *status.Status == databricks.INSTALLED_LibrariesLibraryInstallStatus {
+			continue
+		}
+
+		detail := fmt.Sprintf("%s is %s", libraryKey(*status.Library), *status.Status)
+		if len(status.Messages) > 0 {
+			detail += ": " + strings.Join(status.Messages, "; ")
+		}
+		details = append(details, detail)
+	}
+
+	if len(details) == 0 {
+		return fmt.Errorf("error installing libraries on cluster %s: %s", clusterId, err)
+	}
+
+	return fmt.Errorf("error installing libraries on cluster %s: %s (%s)", clusterId, err, strings.Join(details, ", "))
+}
+
+// diffLibraries returns the libraries to install and to uninstall to get from oldLibraries to newLibraries. Libraries are
+// compared by value, so reordering them changes nothing.
+func diffLibraries(oldLibraries, newLibraries []databricks.Library) (install, uninstall []databricks.Library) {
+	oldKeys := make(map[string]bool)
+	for _, library := range oldLibraries {
+		oldKeys[libraryKey(library)] = true
+	}
+
+	newKeys := make(map[string]bool)
+	for _, library := range newLibraries {
+		newKeys[libraryKey(library)] = true
+		if !oldKeys[libraryKey(library)] {
+			install = append(install, library)
+		}
+	}
+
+	for _, library := range oldLibraries {
+		if !newKeys[libraryKey(library)] {
+			uninstall = append(uninstall, library)
+		}
+	}
+
+	return install, uninstall
+}
+
+// managedLibraries returns the installed libraries that are in managed, in the same order, so that libraries
+// installed by other resources or in the UI and the API returning them in a different order do not show up
+// as changes.
+func managedLibraries(installed, managed []databricks.Library) []databricks.Library {
+	installedKeys := make(map[string]databricks.Library)
+	for _, library := range installed {
+		installedKeys[libraryKey(library)] = library
+	}
+
+	result := make([]databricks.Library, 0, len(managed))
+	for _, library := range managed {
+		if installedLibrary, ok := installedKeys[libraryKey(library)]; ok {
+			result = append(result, installedLibrary)
+		}
+	}
+
+	return result
+}
+
+// libraryKey identifies a library by its JSON representation, e.g. {"pypi":{"package":"simplejson"}}.
+func libraryKey(library databricks.Library) string {
+	b, _ := json.Marshal(library)
+	return string(b)
+}
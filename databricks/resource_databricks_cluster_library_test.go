@@ -0,0 +1,112 @@
+package databricks
+
+import (
+	"errors"
+	"github.com/cattail/databricks-sdk-go/databricks"
+	"strings"
+	"testing"
+)
+
+func testLibraries() (databricks.Library, databricks.Library, databricks.Library) {
+	jar := databricks.Library{Jar: "dbfs:/FileStore/jars/app.jar"}
+	pypi := databricks.Library{Pypi: &databricks.PythonPyPiLibrary{Package_: "simplejson"}}
+	maven := databricks.Library{Maven: &databricks.MavenLibrary{Coordinates: "org.jsoup:jsoup:1.7.2"}}
+	return jar, pypi, maven
+}
+
+func TestDatabricksClusterLibrary_diffLibraries(t *testing.T) {
+	jar, pypi, maven := testLibraries()
+
+	install, uninstall := diffLibraries(
+		[]databricks.Library{jar, pypi},
+		[]databricks.Library{maven, pypi})
+
+	if len(install) != 1 || libraryKey(install[0]) != libraryKey(maven) {
+		t.Fatalf("expected only the maven library to be installed, got %v", install)
+	}
+
+	if len(uninstall) != 1 || libraryKey(uninstall[0]) != libraryKey(jar) {
+		t.Fatalf("expected only the jar to be uninstalled, got %v", uninstall)
+	}
+
+	install, uninstall = diffLibraries(
+		[]databricks.Library{jar, pypi},
+		[]databricks.Library{pypi, jar})
+
+	if len(install) != 0 || len(uninstall) != 0 {
+		t.Fatalf("reordering libraries should change nothing, got %v and %v", install, uninstall)
+	}
+}
+
+func TestDatabricksClusterLibrary_managedLibraries(t *testing.T) {
+	jar, pypi, maven := testLibraries()
+
+	// maven was installed by another resource, and the managed pypi library was uninstalled
+	managed := managedLibraries(
+		[]databricks.Library{maven, jar},
+		[]databricks.Library{pypi, jar})
+
+	if len(managed) != 1 || libraryKey(managed[0]) != libraryKey(jar) {
+		t.Fatalf("expected only the managed jar, got %v", managed)
+	}
+
+	managed = managedLibraries(
+		[]databricks.Library{maven, pypi, jar},
+		[]databricks.Library{jar, pypi})
+
+	expected := []databricks.Library{jar, pypi}
+	if len(managed) != len(expected) {
+		t.Fatalf("expected %d libraries, got %v", len(expected), managed)
+	}
+	for i := range expected {
+		if libraryKey(managed[i]) != libraryKey(expected[i]) {
+			t.Fatalf("expected %s at position %d, got %s", libraryKey(expected[i]), i, libraryKey(managed[i]))
+		}
+	}
+
+	// all the managed libraries were uninstalled out of band, the next plan installs them again
+	if managed := managedLibraries([]databricks.Library{maven}, []databricks.Library{jar, pypi}); len(managed) != 0 {
+		t.Fatalf("expected no managed libraries to be installed, got %v", managed)
+	}
+
+	// once the list is empty after such a drift, libraries installed by others are still left out
+	if managed := managedLibraries([]databricks.Library{maven, pypi}, nil); len(managed) != 0 {
+		t.Fatalf("expected libraries that are not managed to be left out, got %v", managed)
+	}
+}
+
+func TestDatabricksClusterLibrary_installState(t *testing.T) {
+	jar, pypi, _ := testLibraries()
+
+	installed := databricks.INSTALLED_LibrariesLibraryInstallStatus
+	installing := databricks.INSTALLING_LibrariesLibraryInstallStatus
+	failed := databricks.FAILED_LibrariesLibraryInstallStatus
+
+	statuses := []databricks.LibrariesLibraryFullStatus{
+		{Library: &jar, Status: &installed},
+		{Library: &pypi, Status: &installing},
+	}
+	if state := librariesInstallState(statuses, 2); state != "INSTALLING" {
+		t.Fatalf("expected INSTALLING, got %s", state)
+	}
+
+	statuses[1].Status = &installed
+	if state := librariesInstallState(statuses, 2); state != "INSTALLED" {
+		t.Fatalf("expected INSTALLED, got %s", state)
+	}
+
+	statuses[1].Status = &failed
+	statuses[1].Messages = []string{"Could not find a version that satisfies the requirement"}
+	if state := librariesInstallState(statuses, 2); state != "FAILED" {
+		t.Fatalf("expected FAILED, got %s", state)
+	}
+
+	err := librariesInstallError("1234-567890-abc123", statuses, errors.New("unexpected state 'FAILED'"))
+	if !strings.Contains(err.Error(), "simplejson") || !strings.Contains(err.Error(), "Could not find a version") {
+		t.Fatalf("expected the failed library and its messages in the error, got %s", err)
+	}
+
+	if strings.Contains(err.Error(), "app.jar") {
+		t.Fatalf("installed libraries should not be listed in the error, got %s", err)
+	}
+}
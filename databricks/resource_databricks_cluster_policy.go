@@ -0,0 +1,147 @@
+package databricks
+
+import (
+	"fmt"
+	"github.com/cattail/databricks-sdk-go/databricks"
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+	"log"
+	"regexp"
+	"strings"
+)
+
+func resourceDatabricksClusterPolicy() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceDatabricksClusterPolicyCreate,
+		Read:   resourceDatabricksClusterPolicyRead,
+		Update: resourceDatabricksClusterPolicyUpdate,
+		Delete: resourceDatabricksClusterPolicyDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"definition": {
+				Type:             schema.TypeString,
+				Required:         true,
+				ValidateFunc:     validation.ValidateJsonString,
+				DiffSuppressFunc: suppressEquivalentJSON,
+			},
+			"max_clusters_per_user": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntAtLeast(1),
+			},
+		},
+	}
+}
+
+func resourceDatabricksClusterPolicyCreate(d *schema.ResourceData, m interface{}) error {
+	client := m.(*databricksClient).ClusterPolicyApi
+
+	request := databricks.ClusterPoliciesCreateRequest{
+		Name:               d.Get("name").(string),
+		Definition:         d.Get("definition").(string),
+		MaxClustersPerUser: int64(d.Get("max_clusters_per_user").(int)),
+	}
+	logJSON("[DEBUG] Creating cluster policy", request)
+
+	resp, _, err := client.CreatePolicy(nil, request)
+	if err != nil {
+		return newAPIError(err)
+	}
+
+	d.SetId(resp.PolicyId)
+
+	return resourceDatabricksClusterPolicyRead(d, m)
+}
+
+func resourceDatabricksClusterPolicyUpdate(d *schema.ResourceData, m interface{}) error {
+	client := m.(*databricksClient).ClusterPolicyApi
+
+	request := databricks.ClusterPoliciesEditRequest{
+		PolicyId:           d.Id(),
+		Name:               d.Get("name").(string),
+		Definition:         d.Get("definition").(string),
+		MaxClustersPerUser: int64(d.Get("max_clusters_per_user").(int)),
+	}
+	logJSON("[DEBUG] Updating cluster policy", request)
+
+	_, err := client.EditPolicy(nil, request)
+	if err != nil {
+		return newAPIError(err)
+	}
+
+	return resourceDatabricksClusterPolicyRead(d, m)
+}
+
+func resourceDatabricksClusterPolicyDelete(d *schema.ResourceData, m interface{}) error {
+	client := m.(*databricksClient).ClusterPolicyApi
+
+	_, err := client.DeletePolicy(nil, databricks.ClusterPoliciesDeleteRequest{
+		PolicyId: d.Id(),
+	})
+	if err != nil {
+		return newAPIError(err)
+	}
+
+	d.SetId("")
+
+	return nil
+}
+
+func resourceDatabricksClusterPolicyRead(d *schema.ResourceData, m interface{}) error {
+	client := m.(*databricksClient).ClusterPolicyApi
+
+	resp, _, err := client.GetPolicy(nil, d.Id())
+	if err != nil {
+		err = newAPIError(err)
+		if isNotFoundError(err) {
+			log.Printf("[WARN] Cluster policy (%s) not found, removing from state", d.Id())
+			d.SetId("")
+			return nil
+		}
+		return err
+	}
+
+	err = d.Set("name", resp.Name)
+	if err != nil {
+		return err
+	}
+
+	err = d.Set("definition", resp.Definition)
+	if err != nil {
+		return err
+	}
+
+	return d.Set("max_clusters_per_user", int(resp.MaxClustersPerUser))
+}
+
+var clusterPolicyViolationRegexp = regexp.MustCompile(`^Validation failed for ([^,]+), (.+)$`)
+
+// clusterPolicyError turns the API rejecting a cluster because of its policy into one line per violated
+// setting, e.g. "autotermination_minutes: the value must be present". Other errors are returned unchanged.
+func clusterPolicyError(policyId string, err error) error {
+	e, ok := err.(*apiError)
+	if !ok || policyId == "" || !strings.Contains(e.Message, "Validation failed for") {
+		return err
+	}
+
+	violations := make([]string, 0)
+	message := strings.TrimPrefix(e.Message, "Cluster validation error: ")
+	for _, violation := range strings.Split(message, ";") {
+		violation = strings.TrimSpace(violation)
+		if match := clusterPolicyViolationRegexp.FindStringSubmatch(violation); match != nil {
+			violation = fmt.Sprintf("%s: %s", match[1], match[2])
+		}
+		if violation != "" {
+			violations = append(violations, "  - "+violation)
+		}
+	}
+
+	return fmt.Errorf("cluster does not comply with policy %s:\n%s", policyId, strings.Join(violations, "\n"))
+}
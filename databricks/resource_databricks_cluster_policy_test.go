@@ -0,0 +1,54 @@
+package databricks
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDatabricksClusterPolicy_suppressEquivalentDefinition(t *testing.T) {
+	oldDefinition := `{"spark_version":{"type":"fixed","value":"6.4.x-scala2.11"},"autotermination_minutes":{"type":"range","maxValue":60}}`
+	newDefinition := `{
+  "autotermination_minutes": {"maxValue": 60, "type": "range"},
+  "spark_version": {"type": "fixed", "value": "6.4.x-scala2.11"}
+}`
+
+	if !suppressEquivalentJSON("definition", oldDefinition, newDefinition, nil) {
+		t.Fatal("reformatted and reordered JSON should not produce a diff")
+	}
+
+	changed := strings.Replace(newDefinition, "60", "120", 1)
+	if suppressEquivalentJSON("definition", oldDefinition, changed, nil) {
+		t.Fatal("a changed value should produce a diff")
+	}
+
+	if suppressEquivalentJSON("definition", "", newDefinition, nil) {
+		t.Fatal("a new definition should produce a diff")
+	}
+}
+
+func TestDatabricksClusterPolicy_policyError(t *testing.T) {
+	err := clusterPolicyError("ABC123", &apiError{
+		Status:    "400 Bad Request",
+		ErrorCode: errorCodeInvalidParameterValue,
+		Message: "Cluster validation error: Validation failed for autotermination_minutes, the value must be present; " +
+			"Validation failed for spark_version, the value must be 6.4.x-scala2.11 (is \"5.5.x-scala2.11\")",
+	})
+
+	expected := "cluster does not comply with policy ABC123:\n" +
+		"  - autotermination_minutes: the value must be present\n" +
+		"  - spark_version: the value must be 6.4.x-scala2.11 (is \"5.5.x-scala2.11\")"
+	if err.Error() != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, err)
+	}
+
+	other := errors.New("connection refused")
+	if clusterPolicyError("ABC123", other) != other {
+		t.Fatal("errors unrelated to the policy should be returned unchanged")
+	}
+
+	notFound := &apiError{ErrorCode: errorCodeInvalidParameterValue, Message: "Cluster 1234 does not exist"}
+	if clusterPolicyError("", notFound) != notFound {
+		t.Fatal("errors of clusters without a policy should be returned unchanged")
+	}
+}
@@ -4,9 +4,12 @@ import (
 	"errors"
 	"fmt"
 	"github.com/cattail/databricks-sdk-go/databricks"
+	"github.com/hashicorp/terraform/config"
 	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/terraform"
-	"net/http"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -21,7 +24,7 @@ func TestAccDatabricksCluster_basic(t *testing.T) {
 				Check: resource.ComposeAggregateTestCheckFunc(
 					testAccCheckDatabricksClusterExists("databricks_cluster.cluster"),
 					resource.TestCheckResourceAttr(
-						"databricks_cluster.cluster", "name", "tf-test-cluster"),
+						"databricks_cluster.cluster", "cluster_name", "tf-test-cluster"),
 					resource.TestCheckResourceAttr(
 						"databricks_cluster.cluster", "spark_version", "4.2.x-scala2.11"),
 					resource.TestCheckResourceAttr(
@@ -37,7 +40,7 @@ func TestAccDatabricksCluster_basic(t *testing.T) {
 				Check: resource.ComposeAggregateTestCheckFunc(
 					testAccCheckDatabricksClusterExists("databricks_cluster.cluster"),
 					resource.TestCheckResourceAttr(
-						"databricks_cluster.cluster", "name", "tf-test-cluster-renamed"),
+						"databricks_cluster.cluster", "cluster_name", "tf-test-cluster-renamed"),
 					resource.TestCheckResourceAttr(
 						"databricks_cluster.cluster", "spark_version", "4.2.x-scala2.11"),
 					resource.TestCheckResourceAttr(
@@ -52,6 +55,141 @@ func TestAccDatabricksCluster_basic(t *testing.T) {
 	})
 }
 
+func TestAccDatabricksCluster_importBasic(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckDatabricksClusterDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDatabricksClusterConfig(),
+			},
+			{
+				ResourceName:      "databricks_cluster.cluster",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func TestAccDatabricksCluster_terminateOnDestroy(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckDatabricksClusterTerminated,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDatabricksClusterConfigTerminateOnDestroy(),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					testAccCheckDatabricksClusterExists("databricks_cluster.cluster"),
+					resource.TestCheckResourceAttr(
+						"databricks_cluster.cluster", "permanently_delete", "false"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccDatabricksCluster_resizeWithoutRestart(t *testing.T) {
+	var startTime string
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckDatabricksClusterDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDatabricksClusterConfigWorkers(1),
+				Check: func(s *terraform.State) error {
+					startTime = s.RootModule().Resources["databricks_cluster.cluster"].Primary.Attributes["start_time"]
+					return nil
+				},
+			},
+			{
+				Config: testAccDatabricksClusterConfigWorkers(2),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr(
+						"databricks_cluster.cluster", "num_workers", "2"),
+					func(s *terraform.State) error {
+						// a restarted cluster gets a new start time
+						v := s.RootModule().Resources["databricks_cluster.cluster"].Primary.Attributes["start_time"]
+						if v != startTime {
+							return fmt.Errorf("expected the cluster to be resized without a restart, start time changed from %s to %s", startTime, v)
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+func TestAccDatabricksCluster_pinned(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckDatabricksClusterDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDatabricksClusterConfigPinned(true),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					testAccCheckDatabricksClusterPinned("databricks_cluster.cluster", true),
+					resource.TestCheckResourceAttr(
+						"databricks_cluster.cluster", "is_pinned", "true"),
+				),
+			},
+			{
+				Config: testAccDatabricksClusterConfigPinned(false),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					testAccCheckDatabricksClusterPinned("databricks_cluster.cluster", false),
+					resource.TestCheckResourceAttr(
+						"databricks_cluster.cluster", "is_pinned", "false"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccDatabricksCluster_azureAttributes(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckDatabricksClusterDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDatabricksClusterConfigAzureAttributes(),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					testAccCheckDatabricksClusterExists("databricks_cluster.cluster"),
+					resource.TestCheckResourceAttr(
+						"databricks_cluster.cluster", "azure_attributes.0.availability", "ON_DEMAND_AZURE"),
+					resource.TestCheckResourceAttr(
+						"databricks_cluster.cluster", "azure_attributes.0.first_on_demand", "1"),
+				),
+			},
+		},
+	})
+}
+
+func testAccCheckDatabricksClusterPinned(n string, pinned bool) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		client := testAccProvider.Meta().(*databricksClient)
+
+		clusterId := s.RootModule().Resources[n].Primary.ID
+
+		v, err := resourceDatabricksClusterIsPinned(client.ClusterApi, clusterId)
+		if err != nil {
+			return err
+		}
+
+		if v != pinned {
+			return fmt.Errorf("expected cluster %s to have pinned status %t, got %t", clusterId, pinned, v)
+		}
+
+		return nil
+	}
+}
+
 func testAccCheckDatabricksClusterExists(n string) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
 		rs, ok := s.RootModule().Resources[n]
@@ -63,11 +201,11 @@ func testAccCheckDatabricksClusterExists(n string) resource.TestCheckFunc {
 			return fmt.Errorf("no ID is set")
 		}
 
-		client := testAccProvider.Meta().(*databricks.APIClient)
+		client := testAccProvider.Meta().(*databricksClient)
 
 		_, _, err := client.ClusterApi.GetCluster(nil, rs.Primary.ID)
 		if err != nil {
-			return nil
+			return newAPIError(err)
 		}
 
 		return nil
@@ -75,26 +213,48 @@ func testAccCheckDatabricksClusterExists(n string) resource.TestCheckFunc {
 }
 
 func testAccCheckDatabricksClusterDestroy(s *terraform.State) error {
-	client := testAccProvider.Meta().(*databricks.APIClient)
+	client := testAccProvider.Meta().(*databricksClient)
 
 	clusterId := s.RootModule().Resources["databricks_cluster.cluster"].Primary.ID
 
-	_, httpResponse, err := client.ClusterApi.GetCluster(nil, clusterId)
+	_, _, err := client.ClusterApi.GetCluster(nil, clusterId)
 	if err == nil {
 		return errors.New("cluster still exists")
 	}
 
-	if !resourceDatabricksClusterNotExistsError(httpResponse) {
+	if err = newAPIError(err); !isNotFoundError(err) {
 		return err
 	}
 
 	return nil
 }
 
+// testAccCheckDatabricksClusterTerminated checks that the cluster was kept in TERMINATED state, then
+// permanently deletes it.
+func testAccCheckDatabricksClusterTerminated(s *terraform.State) error {
+	client := testAccProvider.Meta().(*databricksClient)
+
+	clusterId := s.RootModule().Resources["databricks_cluster.cluster"].Primary.ID
+
+	resp, _, err := client.ClusterApi.GetCluster(nil, clusterId)
+	if err != nil {
+		return newAPIError(err)
+	}
+
+	if resp.State == nil || *resp.State != databricks.TERMINATED_ClustersClusterState {
+		return fmt.Errorf("expected cluster %s to be terminated, got %v", clusterId, resp.State)
+	}
+
+	_, err = client.ClusterApi.PermanentDeleteCluster(nil, databricks.ClustersPermanentDeleteRequest{
+		ClusterId: clusterId,
+	})
+	return newAPIError(err)
+}
+
 func testAccDatabricksClusterConfig() string {
 	return `
 resource "databricks_cluster" "cluster" {
-	name                    = "tf-test-cluster"
+	cluster_name            = "tf-test-cluster"
 	spark_version           = "4.2.x-scala2.11"
 	node_type_id            = "Standard_D3_v2"
 	num_workers             = 1
@@ -107,7 +267,7 @@ resource "databricks_cluster" "cluster" {
 func testAccDatabricksClusterConfigUpdate() string {
 	return `
 resource "databricks_cluster" "cluster" {
-	name                    = "tf-test-cluster-renamed"
+	cluster_name            = "tf-test-cluster-renamed"
 	spark_version           = "4.2.x-scala2.11"
 	node_type_id            = "Standard_D3_v2"
 	num_workers             = 2
@@ -117,12 +277,809 @@ resource "databricks_cluster" "cluster" {
 `
 }
 
+func testAccDatabricksClusterConfigWorkers(numWorkers int) string {
+	return fmt.Sprintf(`
+resource "databricks_cluster" "cluster" {
+	cluster_name            = "tf-test-cluster-resize"
+	spark_version           = "4.2.x-scala2.11"
+	node_type_id            = "Standard_D3_v2"
+	num_workers             = %d
+	autotermination_minutes = 10
+}
+`, numWorkers)
+}
+
+func testAccDatabricksClusterConfigPinned(pinned bool) string {
+	return fmt.Sprintf(`
+resource "databricks_cluster" "cluster" {
+	cluster_name            = "tf-test-cluster-pinned"
+	spark_version           = "4.2.x-scala2.11"
+	node_type_id            = "Standard_D3_v2"
+	num_workers             = 1
+	autotermination_minutes = 10
+	no_wait                 = true
+	is_pinned               = %t
+}
+`, pinned)
+}
+
+func testAccDatabricksClusterConfigAzureAttributes() string {
+	return `
+resource "databricks_cluster" "cluster" {
+	cluster_name            = "tf-test-cluster-azure"
+	spark_version           = "4.2.x-scala2.11"
+	node_type_id            = "Standard_D3_v2"
+	num_workers             = 1
+	autotermination_minutes = 10
+	no_wait                 = true
+
+	azure_attributes {
+		availability    = "ON_DEMAND_AZURE"
+		first_on_demand = 1
+	}
+}
+`
+}
+
+func testAccDatabricksClusterConfigTerminateOnDestroy() string {
+	return `
+resource "databricks_cluster" "cluster" {
+	cluster_name            = "tf-test-cluster-terminate"
+	spark_version           = "4.2.x-scala2.11"
+	node_type_id            = "Standard_D3_v2"
+	num_workers             = 1
+	autotermination_minutes = 10
+	permanently_delete      = false
+}
+`
+}
+
+// testSwaggerError mimics the error returned by the SDK for non-2xx responses.
+type testSwaggerError struct {
+	status string
+	body   string
+}
+
+func (e testSwaggerError) Error() string { return e.status }
+func (e testSwaggerError) Body() []byte  { return []byte(e.body) }
+
 func TestDatabricksCluster_handlesNonExistingClusterError(t *testing.T) {
-	if resourceDatabricksClusterNotExistsError(&http.Response{StatusCode: 300}) {
-		t.Fatal("An error was incorrectly classified as non-existing-cluster error")
+	notFound := []error{
+		testSwaggerError{"400 Bad Request", `{"error_code":"INVALID_PARAMETER_VALUE","message":"Cluster 1234-567890-abc123 does not exist"}`},
+		testSwaggerError{"404 Not Found", `{"error_code":"RESOURCE_DOES_NOT_EXIST","message":"Job 42 does not exist."}`},
+	}
+	for _, err := range notFound {
+		if !isNotFoundError(newAPIError(err)) {
+			t.Fatalf("A non-existing-cluster error was not detected: %s", newAPIError(err))
+		}
+	}
+
+	found := []error{
+		testSwaggerError{"400 Bad Request", `{"error_code":"INVALID_PARAMETER_VALUE","message":"Missing required field: spark_version"}`},
+		testSwaggerError{"401 Unauthorized", `{"error_code":"PERMISSION_DENIED","message":"Invalid access token."}`},
+		testSwaggerError{"403 Forbidden", `{"error_code":"PERMISSION_DENIED","message":"User is not authorized"}`},
+		testSwaggerError{"500 Internal Server Error", `{"error_code":"INTERNAL_ERROR","message":"Unexpected error"}`},
+		testSwaggerError{"502 Bad Gateway", `<html>Bad Gateway</html>`},
+		errors.New("dial tcp: connection refused"),
+	}
+	for _, err := range found {
+		if isNotFoundError(newAPIError(err)) {
+			t.Fatalf("An error was incorrectly classified as non-existing-cluster error: %s", err)
+		}
+	}
+}
+
+func TestDatabricksCluster_clusterStateErrorIncludesReason(t *testing.T) {
+	state := databricks.TERMINATED_ClustersClusterState
+	code := databricks.ClustersTerminationCode("CLOUD_PROVIDER_LAUNCH_FAILURE")
+
+	err := clusterStateError("1234-567890-abc123", databricks.ClustersClusterInfo{
+		State:        &state,
+		StateMessage: "Instance launch failed",
+		TerminationReason: &databricks.ClustersTerminationReason{
+			Code:       &code,
+			Parameters: map[string]string{"aws_api_error_code": "InsufficientInstanceCapacity"},
+		},
+	}, errors.New("unexpected state 'TERMINATED', wanted target 'RUNNING'"))
+
+	for _, expected := range []string{"1234-567890-abc123", "Instance launch failed", "CLOUD_PROVIDER_LAUNCH_FAILURE", "InsufficientInstanceCapacity"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Fatalf("expected %q in %q", expected, err)
+		}
+	}
+}
+
+func TestDatabricksCluster_desiredState(t *testing.T) {
+	expected := map[databricks.ClustersClusterState]string{
+		databricks.PENDING_ClustersClusterState:     "RUNNING",
+		databricks.RUNNING_ClustersClusterState:     "RUNNING",
+		databricks.RESIZING_ClustersClusterState:    "RUNNING",
+		databricks.TERMINATING_ClustersClusterState: "TERMINATED",
+		databricks.TERMINATED_ClustersClusterState:  "TERMINATED",
+		databricks.ERROR_ClustersClusterState:       "ERROR",
+	}
+
+	for state, desiredState := range expected {
+		if v := resourceDatabricksClusterDesiredState(state); v != desiredState {
+			t.Fatalf("expected %s to map to %s, got %s", state, desiredState, v)
+		}
+	}
+}
+
+func TestDatabricksCluster_changedSettings(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, resourceDatabricksCluster().Schema, map[string]interface{}{
+		"spark_version":     "7.3.x-scala2.12",
+		"num_workers":       4,
+		"restart_on_update": false,
+		"is_pinned":         true,
+	})
+
+	changed := resourceDatabricksClusterChangedSettings(d)
+	if !reflect.DeepEqual(changed, []string{"num_workers", "spark_version"}) {
+		t.Fatalf("expected only the changed cluster settings, got %v", changed)
+	}
+}
+
+func TestDatabricksCluster_planUpdate(t *testing.T) {
+	cases := []struct {
+		changed         []string
+		running         bool
+		restartOnUpdate bool
+		expected        clusterSettingsUpdate
+	}{
+		{[]string{"num_workers"}, true, true, clusterSettingsUpdate{Resize: true}},
+		{[]string{"autoscale"}, true, false, clusterSettingsUpdate{Resize: true}},
+		{[]string{"num_workers"}, false, true, clusterSettingsUpdate{Edit: true}},
+		{[]string{"num_workers", "spark_version"}, true, true, clusterSettingsUpdate{Edit: true}},
+		{[]string{"spark_version"}, false, false, clusterSettingsUpdate{Edit: true}},
+		{[]string{"spark_version"}, true, false, clusterSettingsUpdate{Deferred: []string{"spark_version"}}},
+		{[]string{"num_workers", "spark_conf"}, true, false, clusterSettingsUpdate{Resize: true, Deferred: []string{"spark_conf"}}},
+		{[]string{}, true, true, clusterSettingsUpdate{}},
+	}
+
+	for _, c := range cases {
+		update := resourceDatabricksClusterPlanUpdate(c.changed, c.running, c.restartOnUpdate)
+		if !reflect.DeepEqual(update, c.expected) {
+			t.Fatalf("%v (running: %t, restart_on_update: %t): expected %+v, got %+v",
+				c.changed, c.running, c.restartOnUpdate, c.expected, update)
+		}
+	}
+}
+
+func TestDatabricksCluster_stagedSettings(t *testing.T) {
+	// spark_conf was staged while the cluster ran, and spark_version changed since
+	changed := resourceDatabricksClusterStagedSettings([]string{"num_workers", "spark_version"}, []string{"spark_conf", "spark_version"})
+	if !reflect.DeepEqual(changed, []string{"num_workers", "spark_conf", "spark_version"}) {
+		t.Fatalf("expected the staged settings to be applied along with the changed ones, got %v", changed)
+	}
+
+	update := resourceDatabricksClusterPlanUpdate(changed, true, false)
+	if !reflect.DeepEqual(update, clusterSettingsUpdate{Resize: true, Deferred: []string{"spark_conf", "spark_version"}}) {
+		t.Fatalf("expected the settings to stay staged while the cluster runs, got %+v", update)
+	}
+
+	update = resourceDatabricksClusterPlanUpdate(resourceDatabricksClusterStagedSettings(nil, update.Deferred), false, false)
+	if !reflect.DeepEqual(update, clusterSettingsUpdate{Edit: true}) {
+		t.Fatalf("expected the staged settings to be applied once the cluster is terminated, got %+v", update)
+	}
+}
+
+func TestDatabricksCluster_flattenRuntimeAttributes(t *testing.T) {
+	if driver := resourceDatabricksClusterFlattenDriver(nil); len(driver) != 0 {
+		t.Fatalf("expected no driver, got %v", driver)
+	}
+
+	driver := resourceDatabricksClusterFlattenDriver(&databricks.ClustersSparkNode{
+		PrivateIp:      "10.0.0.1",
+		NodeId:         "abc",
+		StartTimestamp: 1500000000000,
+	})
+	if len(driver) != 1 || driver[0]["private_ip"] != "10.0.0.1" || driver[0]["start_timestamp"] != 1500000000000 {
+		t.Fatalf("unexpected driver %v", driver)
+	}
+
+	if reason := resourceDatabricksClusterFlattenTerminationReason(&databricks.ClustersTerminationReason{}); len(reason) != 0 {
+		t.Fatalf("expected no termination reason, got %v", reason)
+	}
+
+	code := databricks.ClustersTerminationCode("INACTIVITY")
+	reason := resourceDatabricksClusterFlattenTerminationReason(&databricks.ClustersTerminationReason{
+		Code:       &code,
+		Parameters: map[string]string{"inactivity_duration_min": "10"},
+	})
+	if len(reason) != 1 || reason[0]["code"] != "INACTIVITY" {
+		t.Fatalf("unexpected termination reason %v", reason)
+	}
+}
+
+func TestDatabricksCluster_initScriptsRoundTrip(t *testing.T) {
+	initScripts := []databricks.ClustersInitScriptInfo{
+		{Dbfs: &databricks.ClustersInitScriptInfoDbfs{Destination: "dbfs:/databricks/init/install-certs.sh"}},
+		{S3: &databricks.ClustersInitScriptInfoS3{
+			Destination:      "s3://my-bucket/init/install-packages.sh",
+			Region:           "eu-west-1",
+			EnableEncryption: true,
+			EncryptionType:   "sse-kms",
+			KmsKey:           "arn:aws:kms:eu-west-1:123456789012:key/abc",
+			CannedAcl:        "bucket-owner-full-control",
+		}},
+	}
+
+	flattened := resourceDatabricksClusterFlattenInitScripts(initScripts)
+	values := make([]interface{}, len(flattened))
+	for i, v := range flattened {
+		values[i] = v
+	}
+
+	expanded := resourceDatabricksClusterExpandInitScripts(values)
+	if len(expanded) != 2 {
+		t.Fatalf("expected 2 init scripts, got %d", len(expanded))
+	}
+
+	if expanded[0].Dbfs == nil || expanded[0].Dbfs.Destination != initScripts[0].Dbfs.Destination || expanded[0].S3 != nil {
+		t.Fatalf("unexpected dbfs init script %v", expanded[0])
 	}
 
-	if !resourceDatabricksClusterNotExistsError(&http.Response{StatusCode: 400}) {
-		t.Fatal("A non-existing-cluster error was not detected")
+	if expanded[1].S3 == nil || *expanded[1].S3 != *initScripts[1].S3 || expanded[1].Dbfs != nil {
+		t.Fatalf("unexpected s3 init script %v", expanded[1])
 	}
 }
+
+func TestDatabricksCluster_flattenDockerImageKeepsPassword(t *testing.T) {
+	current := []interface{}{
+		map[string]interface{}{
+			"url": "my-registry.example.com/spark:old",
+			"basic_auth": []interface{}{
+				map[string]interface{}{"username": "deploy", "password": "secret"},
+			},
+		},
+	}
+
+	// the password is never returned by the API
+	dockerImage := resourceDatabricksClusterFlattenDockerImage(&databricks.ClustersDockerImage{
+		Url:       "my-registry.example.com/spark:new",
+		BasicAuth: &databricks.ClustersDockerBasicAuth{Username: "deploy"},
+	}, current)
+
+	if len(dockerImage) != 1 || dockerImage[0]["url"] != "my-registry.example.com/spark:new" {
+		t.Fatalf("unexpected docker image %v", dockerImage)
+	}
+
+	basicAuth := dockerImage[0]["basic_auth"].([]interface{})[0].(map[string]interface{})
+	if basicAuth["password"] != "secret" {
+		t.Fatalf("expected the password to be kept from state, got %v", basicAuth)
+	}
+
+	// a username changed outside of Terraform shows up as a change
+	dockerImage = resourceDatabricksClusterFlattenDockerImage(&databricks.ClustersDockerImage{
+		Url:       "my-registry.example.com/spark:new",
+		BasicAuth: &databricks.ClustersDockerBasicAuth{Username: "ci"},
+	}, current)
+
+	basicAuth = dockerImage[0]["basic_auth"].([]interface{})[0].(map[string]interface{})
+	if basicAuth["username"] != "ci" || basicAuth["password"] != "secret" {
+		t.Fatalf("expected the username from the API and the password from state, got %v", basicAuth)
+	}
+
+	if v := resourceDatabricksClusterFlattenDockerImage(nil, current); len(v) != 0 {
+		t.Fatalf("expected no docker image, got %v", v)
+	}
+}
+
+func TestDatabricksCluster_validateCloudAttributes(t *testing.T) {
+	newCluster := map[string]interface{}{
+		"spark_version":    "6.4.x-scala2.11",
+		"node_type_id":     "Standard_D3_v2",
+		"num_workers":      2,
+		"azure_attributes": []interface{}{map[string]interface{}{"availability": "SPOT_AZURE"}},
+	}
+	raw := map[string]interface{}{
+		"name":        "my-job",
+		"new_cluster": []interface{}{newCluster},
+	}
+	if _, err := testResourceDiff(t, resourceDatabricksJob(), nil, raw); err != nil {
+		t.Fatalf("a single cloud block should be accepted, got %s", err)
+	}
+
+	newCluster["aws_attributes"] = []interface{}{map[string]interface{}{"zone_id": "us-west-2a"}}
+	_, err := testResourceDiff(t, resourceDatabricksJob(), nil, raw)
+	if err == nil || !strings.Contains(err.Error(), "new_cluster.0.aws_attributes, new_cluster.0.azure_attributes") {
+		t.Fatalf("expected an error naming both blocks, got %v", err)
+	}
+}
+
+func TestDatabricksCluster_validateWorkspaceCloud(t *testing.T) {
+	c, err := config.NewRawConfig(map[string]interface{}{
+		"spark_version":    "6.4.x-scala2.11",
+		"node_type_id":     "Standard_D3_v2",
+		"num_workers":      2,
+		"azure_attributes": []interface{}{map[string]interface{}{"availability": "SPOT_AZURE"}},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	azure := &databricksClient{Cloud: "azure"}
+	if _, err := resourceDatabricksCluster().Diff(nil, terraform.NewResourceConfig(c), azure); err != nil {
+		t.Fatalf("the block of the cloud of the workspace should be accepted, got %s", err)
+	}
+
+	aws := &databricksClient{Cloud: "aws"}
+	_, err = resourceDatabricksCluster().Diff(nil, terraform.NewResourceConfig(c), aws)
+	if err == nil || !strings.Contains(err.Error(), "use aws_attributes instead") {
+		t.Fatalf("expected the block of another cloud to be rejected, got %v", err)
+	}
+}
+
+func TestDatabricksCluster_validateSpotBidMaxPrice(t *testing.T) {
+	for _, price := range []float64{-1, 0.5, 100} {
+		if _, errs := validateSpotBidMaxPrice(price, "spot_bid_max_price"); len(errs) != 0 {
+			t.Fatalf("expected %v to be valid, got %v", price, errs)
+		}
+	}
+
+	for _, price := range []float64{0, -2} {
+		if _, errs := validateSpotBidMaxPrice(price, "spot_bid_max_price"); len(errs) == 0 {
+			t.Fatalf("expected %v to be invalid", price)
+		}
+	}
+}
+
+func TestDatabricksCluster_azureAttributesRoundTrip(t *testing.T) {
+	availability := databricks.SPOT_WITH_FALLBACK_AZURE_ClustersAzureAvailability
+	azureAttributes := databricks.ClustersAzureAttributes{
+		Availability:    &availability,
+		FirstOnDemand:   2,
+		SpotBidMaxPrice: -1,
+	}
+
+	flattened := resourceDatabricksClusterFlattenAzureAttributes(&azureAttributes)
+	expanded := resourceDatabricksClusterExpandAzureAttributes([]interface{}{flattened[0]})
+
+	if expanded.Availability == nil || *expanded.Availability != availability ||
+		expanded.FirstOnDemand != 2 || expanded.SpotBidMaxPrice != -1 {
+		t.Fatalf("unexpected azure attributes %v", expanded)
+	}
+}
+
+func TestDatabricksCluster_flattenAwsAttributesWithoutVolumeType(t *testing.T) {
+	availability := databricks.SPOT_WITH_FALLBACK_ClustersAwsAvailability
+	awsAttributes := resourceDatabricksClusterFlattenAwsAttributes(&databricks.ClustersAwsAttributes{
+		ZoneId:              "us-west-2a",
+		EbsVolumeCount:      1,
+		EbsVolumeSize:       100,
+		FirstOnDemand:       1,
+		Availability:        &availability,
+		SpotBidPricePercent: 100,
+	})
+
+	if len(awsAttributes) != 1 {
+		t.Fatalf("expected aws attributes, got %v", awsAttributes)
+	}
+
+	attrs := awsAttributes[0]
+	if attrs["ebs_volume_count"] != 1 || attrs["ebs_volume_size"] != 100 {
+		t.Fatalf("the EBS volume count and size should be kept without a volume type, got %v", attrs)
+	}
+
+	if attrs["availability"] != "SPOT_WITH_FALLBACK" || attrs["first_on_demand"] != 1 || attrs["spot_bid_price_percent"] != 100 {
+		t.Fatalf("unexpected availability settings %v", attrs)
+	}
+}
+
+func TestDatabricksCluster_validateAwsAttributes(t *testing.T) {
+	awsAttributes := map[string]interface{}{"ebs_volume_iops": 3000}
+	raw := map[string]interface{}{
+		"spark_version":  "6.4.x-scala2.11",
+		"node_type_id":   "i3.xlarge",
+		"num_workers":    2,
+		"aws_attributes": []interface{}{awsAttributes},
+	}
+	if _, err := testResourceDiff(t, resourceDatabricksCluster(), nil, raw); err == nil {
+		t.Fatal("expected an error when only the IOPS are set")
+	}
+
+	awsAttributes["ebs_volume_throughput"] = 125
+	if _, err := testResourceDiff(t, resourceDatabricksCluster(), nil, raw); err != nil {
+		t.Fatalf("expected IOPS and throughput to be accepted together, got %s", err)
+	}
+}
+
+func TestDatabricksCluster_validateAwsAttributesNotConfigured(t *testing.T) {
+	// the API reports IOPS for a cluster, without them being configured
+	state := map[string]string{
+		"spark_version":                    "6.4.x-scala2.11",
+		"node_type_id":                     "i3.xlarge",
+		"num_workers":                      "2",
+		"aws_attributes.#":                 "1",
+		"aws_attributes.0.ebs_volume_iops": "3000",
+	}
+	raw := map[string]interface{}{
+		"spark_version": "6.4.x-scala2.11",
+		"node_type_id":  "i3.xlarge",
+		"num_workers":   2,
+	}
+	if _, err := testResourceDiff(t, resourceDatabricksCluster(), state, raw); err != nil {
+		t.Fatalf("IOPS not changed by the plan should be accepted, got %s", err)
+	}
+
+	raw["aws_attributes"] = []interface{}{map[string]interface{}{
+		"ebs_volume_iops":       config.UnknownVariableValue,
+		"ebs_volume_throughput": 125,
+	}}
+	if _, err := testResourceDiff(t, resourceDatabricksCluster(), nil, raw); err != nil {
+		t.Fatalf("IOPS only known at apply time should be accepted, got %s", err)
+	}
+}
+
+func TestDatabricksCluster_validateNodeType(t *testing.T) {
+	newCluster := map[string]interface{}{
+		"spark_version": "6.4.x-scala2.11",
+		"num_workers":   2,
+	}
+	raw := map[string]interface{}{
+		"name":        "my-job",
+		"new_cluster": []interface{}{newCluster},
+	}
+	if _, err := testResourceDiff(t, resourceDatabricksJob(), nil, raw); err == nil {
+		t.Fatal("expected an error without a node type or an instance pool")
+	}
+
+	newCluster["driver_instance_pool_id"] = "0101-120000-brick1-pool-ABCD1234"
+	if _, err := testResourceDiff(t, resourceDatabricksJob(), nil, raw); err == nil {
+		t.Fatal("expected an error for a driver pool without an instance pool")
+	}
+
+	newCluster["instance_pool_id"] = "0101-120000-brick1-pool-ABCD1234"
+	if _, err := testResourceDiff(t, resourceDatabricksJob(), nil, raw); err != nil {
+		t.Fatalf("a pooled cluster should not need a node type, got %s", err)
+	}
+}
+
+func TestDatabricksCluster_validateDriverInstancePool(t *testing.T) {
+	// the API defaults the driver pool to the pool of the workers, which is read back into the state
+	pooled := map[string]string{
+		"spark_version":           "6.4.x-scala2.11",
+		"node_type_id":            "i3.xlarge",
+		"num_workers":             "2",
+		"instance_pool_id":        "0101-120000-brick1-pool-ABCD1234",
+		"driver_instance_pool_id": "0101-120000-brick1-pool-ABCD1234",
+	}
+	raw := map[string]interface{}{
+		"spark_version":    "6.4.x-scala2.11",
+		"num_workers":      2,
+		"instance_pool_id": "0101-120000-brick1-pool-ABCD1234",
+	}
+
+	diff, err := testResourceDiff(t, resourceDatabricksCluster(), pooled, raw)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if attr, ok := diff.Attributes["driver_instance_pool_id"]; ok {
+		t.Fatalf("expected the default driver pool to be ignored, got %v", attr)
+	}
+
+	raw["instance_pool_id"] = "0101-120000-brick1-pool-EFGH5678"
+	diff, err = testResourceDiff(t, resourceDatabricksCluster(), pooled, raw)
+	if err != nil {
+		t.Fatalf("expected the instance pool to be changed, got %s", err)
+	}
+	if attr, ok := diff.Attributes["driver_instance_pool_id"]; !ok || attr.New != "" {
+		t.Fatalf("expected the driver pool of the previous instance pool to be dropped, got %v", attr)
+	}
+
+	delete(raw, "instance_pool_id")
+	raw["node_type_id"] = "i3.xlarge"
+	if _, err := testResourceDiff(t, resourceDatabricksCluster(), pooled, raw); err != nil {
+		t.Fatalf("expected the instance pool to be removed, got %s", err)
+	}
+
+	raw["driver_instance_pool_id"] = "0101-120000-brick1-pool-EFGH5678"
+	if _, err := testResourceDiff(t, resourceDatabricksCluster(), pooled, raw); err == nil {
+		t.Fatal("expected an error for a configured driver pool without an instance pool")
+	}
+}
+
+func TestDatabricksCluster_driverInstancePoolRequiresPool(t *testing.T) {
+	clusterSettings := getClusterSettings(map[string]interface{}{
+		"spark_version":           "6.4.x-scala2.11",
+		"node_type_id":            "i3.xlarge",
+		"driver_instance_pool_id": "0101-120000-brick1-pool-ABCD1234",
+	})
+
+	if clusterSettings.DriverInstancePoolId != "" || clusterSettings.NodeTypeId != "i3.xlarge" {
+		t.Fatalf("expected the driver pool to only be sent with an instance pool, got %v", clusterSettings)
+	}
+}
+
+func TestDatabricksCluster_validateNodeTypeNotYetKnown(t *testing.T) {
+	raw := map[string]interface{}{
+		"spark_version":    "6.4.x-scala2.11",
+		"num_workers":      2,
+		"instance_pool_id": config.UnknownVariableValue,
+	}
+
+	if _, err := testResourceDiff(t, resourceDatabricksCluster(), nil, raw); err != nil {
+		t.Fatalf("an instance pool only known at apply time should be accepted, got %s", err)
+	}
+}
+
+func TestDatabricksCluster_pooledClusterSettings(t *testing.T) {
+	clusterSettings := getClusterSettings(map[string]interface{}{
+		"spark_version":       "6.4.x-scala2.11",
+		"node_type_id":        "i3.xlarge",
+		"driver_node_type_id": "i3.xlarge",
+		"instance_pool_id":    "0101-120000-brick1-pool-ABCD1234",
+	})
+
+	if clusterSettings.InstancePoolId != "0101-120000-brick1-pool-ABCD1234" {
+		t.Fatalf("expected the instance pool to be set, got %q", clusterSettings.InstancePoolId)
+	}
+
+	if clusterSettings.NodeTypeId != "" || clusterSettings.DriverNodeTypeId != "" {
+		t.Fatalf("the node types of a pooled cluster should not be sent, got %q and %q",
+			clusterSettings.NodeTypeId, clusterSettings.DriverNodeTypeId)
+	}
+}
+
+func TestDatabricksCluster_singleNodeRoundTrip(t *testing.T) {
+	clusterSettings := getClusterSettings(map[string]interface{}{
+		"spark_version": "6.4.x-scala2.11",
+		"node_type_id":  "i3.xlarge",
+		"single_node":   true,
+		"spark_conf":    map[string]interface{}{"spark.sql.shuffle.partitions": "8"},
+	})
+
+	if clusterSettings.NumWorkers != 0 || clusterSettings.Autoscale != nil {
+		t.Fatalf("a single node cluster should have no workers, got %v", clusterSettings)
+	}
+
+	if clusterSettings.SparkConf["spark.master"] != "local[*]" || clusterSettings.CustomTags["ResourceClass"] != "SingleNode" {
+		t.Fatalf("expected the single node settings to be added, got %v and %v", clusterSettings.SparkConf, clusterSettings.CustomTags)
+	}
+
+	m := map[string]interface{}{"single_node": true}
+	if err := setClusterSettings(m, clusterSettings); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if m["single_node"] != true {
+		t.Fatal("expected the cluster to be read back as a single node cluster")
+	}
+
+	sparkConf := m["spark_conf"].(map[string]string)
+	if len(sparkConf) != 1 || sparkConf["spark.sql.shuffle.partitions"] != "8" {
+		t.Fatalf("expected only the configured spark_conf to be read back, got %v", sparkConf)
+	}
+
+	if customTags := m["custom_tags"].(map[string]string); len(customTags) != 0 {
+		t.Fatalf("expected no custom_tags to be read back, got %v", customTags)
+	}
+}
+
+func TestDatabricksCluster_singleNodeSettingsWrittenByHand(t *testing.T) {
+	m := map[string]interface{}{
+		"spark_conf": map[string]interface{}{
+			"spark.databricks.cluster.profile": "singleNode",
+			"spark.master":                     "local[*]",
+		},
+		"custom_tags": map[string]interface{}{"ResourceClass": "SingleNode"},
+	}
+	err := setClusterSettings(m, databricks.NewCluster{
+		SparkVersion: "6.4.x-scala2.11",
+		NodeTypeId:   "i3.xlarge",
+		SparkConf:    map[string]string{"spark.databricks.cluster.profile": "singleNode", "spark.master": "local[*]"},
+		CustomTags:   map[string]string{"ResourceClass": "SingleNode"},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if m["single_node"] != false {
+		t.Fatal("expected a cluster without single_node to be read back as it is")
+	}
+
+	if sparkConf := m["spark_conf"].(map[string]string); len(sparkConf) != 2 {
+		t.Fatalf("expected the spark_conf written by hand to be read back, got %v", sparkConf)
+	}
+
+	if customTags := m["custom_tags"].(map[string]string); customTags["ResourceClass"] != "SingleNode" {
+		t.Fatalf("expected the custom_tags written by hand to be read back, got %v", customTags)
+	}
+}
+
+func TestDatabricksCluster_validateSingleNode(t *testing.T) {
+	raw := map[string]interface{}{
+		"spark_version": "6.4.x-scala2.11",
+		"node_type_id":  "i3.xlarge",
+		"single_node":   true,
+		"autoscale":     []interface{}{map[string]interface{}{"min_workers": 1, "max_workers": 2}},
+	}
+	if _, err := testResourceDiff(t, resourceDatabricksCluster(), nil, raw); err == nil {
+		t.Fatal("expected autoscale to be rejected on a single node cluster")
+	}
+
+	delete(raw, "autoscale")
+	if _, err := testResourceDiff(t, resourceDatabricksCluster(), nil, raw); err != nil {
+		t.Fatalf("expected a single node cluster to be accepted, got %s", err)
+	}
+
+	state := map[string]string{
+		"spark_version": "6.4.x-scala2.11",
+		"node_type_id":  "i3.xlarge",
+		"num_workers":   "2",
+	}
+	if _, err := testResourceDiff(t, resourceDatabricksCluster(), state, raw); err != nil {
+		t.Fatalf("expected a cluster with workers to become a single node cluster, got %s", err)
+	}
+}
+
+func TestDatabricksCluster_validateWorkers(t *testing.T) {
+	newCluster := map[string]interface{}{
+		"spark_version": "6.4.x-scala2.11",
+		"node_type_id":  "i3.xlarge",
+		"num_workers":   2,
+	}
+	raw := map[string]interface{}{
+		"name":        "my-job",
+		"new_cluster": []interface{}{newCluster},
+	}
+	if _, err := testResourceDiff(t, resourceDatabricksJob(), nil, raw); err != nil {
+		t.Fatalf("expected num_workers to be accepted, got %s", err)
+	}
+
+	newCluster["autoscale"] = []interface{}{map[string]interface{}{"min_workers": 4, "max_workers": 2}}
+	_, err := testResourceDiff(t, resourceDatabricksJob(), nil, raw)
+	if err == nil || !strings.Contains(err.Error(), "only one of new_cluster.0.num_workers or new_cluster.0.autoscale") {
+		t.Fatalf("expected num_workers and autoscale to conflict, got %v", err)
+	}
+
+	delete(newCluster, "num_workers")
+	_, err = testResourceDiff(t, resourceDatabricksJob(), nil, raw)
+	if err == nil || !strings.Contains(err.Error(), "min_workers (4) can't be greater than max_workers (2)") {
+		t.Fatalf("expected an invalid autoscale range to be rejected, got %v", err)
+	}
+
+	newCluster["autoscale"] = []interface{}{map[string]interface{}{"min_workers": 2, "max_workers": 4}}
+	if _, err := testResourceDiff(t, resourceDatabricksJob(), nil, raw); err != nil {
+		t.Fatalf("expected autoscale to be accepted, got %s", err)
+	}
+}
+
+func TestDatabricksCluster_validateWorkersTransitions(t *testing.T) {
+	fixedSize := map[string]string{
+		"spark_version": "6.4.x-scala2.11",
+		"node_type_id":  "i3.xlarge",
+		"num_workers":   "2",
+	}
+	autoscale := map[string]interface{}{
+		"spark_version": "6.4.x-scala2.11",
+		"node_type_id":  "i3.xlarge",
+		"autoscale":     []interface{}{map[string]interface{}{"min_workers": 2, "max_workers": 8}},
+	}
+
+	diff, err := testResourceDiff(t, resourceDatabricksCluster(), fixedSize, autoscale)
+	if err != nil {
+		t.Fatalf("expected a cluster with a fixed size to move to autoscale, got %s", err)
+	}
+	if _, ok := diff.Attributes["num_workers"]; ok {
+		t.Fatalf("expected num_workers to be ignored next to autoscale, got %v", diff.Attributes["num_workers"])
+	}
+
+	noWorkers := map[string]interface{}{
+		"spark_version": "6.4.x-scala2.11",
+		"node_type_id":  "i3.xlarge",
+		"num_workers":   0,
+	}
+	diff, err = testResourceDiff(t, resourceDatabricksCluster(), fixedSize, noWorkers)
+	if err != nil {
+		t.Fatalf("expected num_workers = 0 to be accepted, got %s", err)
+	}
+	if attr, ok := diff.Attributes["num_workers"]; !ok || attr.New != "0" {
+		t.Fatalf("expected the cluster to be resized to 0 workers, got %v", attr)
+	}
+
+	autoscaleState := map[string]string{
+		"spark_version":           "6.4.x-scala2.11",
+		"node_type_id":            "i3.xlarge",
+		"num_workers":             "0",
+		"autoscale.#":             "1",
+		"autoscale.0.min_workers": "2",
+		"autoscale.0.max_workers": "8",
+	}
+	fixedSizeConfig := map[string]interface{}{
+		"spark_version": "6.4.x-scala2.11",
+		"node_type_id":  "i3.xlarge",
+		"num_workers":   4,
+	}
+	if _, err := testResourceDiff(t, resourceDatabricksCluster(), autoscaleState, fixedSizeConfig); err != nil {
+		t.Fatalf("expected an autoscaling cluster to move to a fixed size, got %s", err)
+	}
+}
+
+func TestDatabricksCluster_validateWorkersNotYetKnown(t *testing.T) {
+	raw := map[string]interface{}{
+		"spark_version": "6.4.x-scala2.11",
+		"node_type_id":  "i3.xlarge",
+		"num_workers":   config.UnknownVariableValue,
+	}
+	if _, err := testResourceDiff(t, resourceDatabricksCluster(), nil, raw); err != nil {
+		t.Fatalf("num_workers only known at apply time should be accepted, got %s", err)
+	}
+
+	delete(raw, "num_workers")
+	raw["autoscale"] = []interface{}{map[string]interface{}{"min_workers": config.UnknownVariableValue, "max_workers": 4}}
+	if _, err := testResourceDiff(t, resourceDatabricksCluster(), nil, raw); err != nil {
+		t.Fatalf("autoscale only known at apply time should be accepted, got %s", err)
+	}
+}
+
+func TestDatabricksCluster_autoscaleSettingsLeaveOutNumWorkers(t *testing.T) {
+	// num_workers still holds the size of the cluster before it was moved to autoscale
+	clusterSettings := getClusterSettings(map[string]interface{}{
+		"spark_version": "6.4.x-scala2.11",
+		"node_type_id":  "i3.xlarge",
+		"num_workers":   2,
+		"autoscale":     []interface{}{map[string]interface{}{"min_workers": 2, "max_workers": 8}},
+	})
+
+	if clusterSettings.NumWorkers != 0 || clusterSettings.Autoscale == nil {
+		t.Fatalf("expected only autoscale to be sent, got %v", clusterSettings)
+	}
+}
+
+func TestDatabricksCluster_autoscaleIgnoresReportedWorkers(t *testing.T) {
+	m := make(map[string]interface{})
+	err := setClusterSettings(m, databricks.NewCluster{
+		SparkVersion: "6.4.x-scala2.11",
+		NodeTypeId:   "i3.xlarge",
+		NumWorkers:   3,
+		Autoscale:    &databricks.ClustersAutoScale{MinWorkers: 2, MaxWorkers: 8},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if m["num_workers"] != int32(0) {
+		t.Fatalf("expected the reported number of workers to be ignored, got %v", m["num_workers"])
+	}
+}
+
+func TestDatabricksCluster_filterManagedKeys(t *testing.T) {
+	sparkEnvVars := map[string]string{
+		"PYSPARK_PYTHON": "/databricks/python3/bin/python3",
+		"JAVA_OPTS":      "-Xss4m",
+	}
+
+	filtered := resourceDatabricksClusterFilterManagedKeys(sparkEnvVars, map[string]interface{}{"JAVA_OPTS": "-Xss4m"}, clusterManagedSparkEnvVars)
+	if len(filtered) != 1 || filtered["JAVA_OPTS"] != "-Xss4m" {
+		t.Fatalf("expected the key added by Databricks to be ignored, got %v", filtered)
+	}
+
+	configured := map[string]interface{}{"PYSPARK_PYTHON": "/databricks/python/bin/python"}
+	filtered = resourceDatabricksClusterFilterManagedKeys(sparkEnvVars, configured, clusterManagedSparkEnvVars)
+	if filtered["PYSPARK_PYTHON"] != "/databricks/python3/bin/python3" {
+		t.Fatalf("expected a configured key to be read back so that changes are detected, got %v", filtered)
+	}
+
+	filtered = resourceDatabricksClusterFilterManagedKeys(sparkEnvVars, nil, clusterManagedSparkEnvVars)
+	if filtered["JAVA_OPTS"] != "-Xss4m" {
+		t.Fatalf("expected keys not managed by Databricks to be read back, got %v", filtered)
+	}
+}
+
+// testResourceDiff plans the raw configuration of a resource against the attributes of its state, with the
+// same diff suppression and CustomizeDiff checks as terraform plan. A nil state plans a new resource.
+func testResourceDiff(t *testing.T, r *schema.Resource, state map[string]string, raw map[string]interface{}) (*terraform.InstanceDiff, error) {
+	c, err := config.NewRawConfig(raw)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var s *terraform.InstanceState
+	if state != nil {
+		s = &terraform.InstanceState{ID: "0101-120000-brick1", Attributes: state}
+	}
+
+	return r.Diff(s, terraform.NewResourceConfig(c), nil)
+}
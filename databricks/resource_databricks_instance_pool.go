@@ -0,0 +1,385 @@
+package databricks
+
+import (
+	"github.com/cattail/databricks-sdk-go/databricks"
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+	"log"
+)
+
+func resourceDatabricksInstancePool() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceDatabricksInstancePoolCreate,
+		Read:   resourceDatabricksInstancePoolRead,
+		Update: resourceDatabricksInstancePoolUpdate,
+		Delete: resourceDatabricksInstancePoolDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		// only the name, sizing and idle termination of a pool can be edited, everything else forces a new pool
+		Schema: map[string]*schema.Schema{
+			"instance_pool_name": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"min_idle_instances": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntAtLeast(0),
+			},
+			"max_capacity": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntAtLeast(1),
+			},
+			"idle_instance_autotermination_minutes": {
+				Type:         schema.TypeInt,
+				Required:     true,
+				ValidateFunc: validation.IntAtLeast(0),
+			},
+			"node_type_id": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"preloaded_spark_versions": {
+				Type:     schema.TypeList,
+				Optional: true,
+				ForceNew: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"custom_tags": {
+				Type:     schema.TypeMap,
+				Optional: true,
+				ForceNew: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"enable_elastic_disk": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Computed: true,
+				ForceNew: true,
+			},
+			"aws_attributes": {
+				Type:     schema.TypeList,
+				Optional: true,
+				// Databricks defaults to spot instances bid at 100% of the on-demand price, in a zone it picks
+				Computed:      true,
+				ForceNew:      true,
+				MaxItems:      1,
+				ConflictsWith: []string{"azure_attributes"},
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"availability": {
+							Type:     schema.TypeString,
+							Optional: true,
+							Computed: true,
+							ForceNew: true,
+							ValidateFunc: validation.StringInSlice([]string{
+								string(databricks.SPOT_ClustersAwsAvailability),
+								string(databricks.ON_DEMAND_ClustersAwsAvailability),
+							}, false),
+						},
+						"zone_id": {
+							Type:     schema.TypeString,
+							Optional: true,
+							Computed: true,
+							ForceNew: true,
+						},
+						"spot_bid_price_percent": {
+							Type:         schema.TypeInt,
+							Optional:     true,
+							Computed:     true,
+							ForceNew:     true,
+							ValidateFunc: validation.IntBetween(1, 10000),
+						},
+					},
+				},
+			},
+			"azure_attributes": {
+				Type:     schema.TypeList,
+				Optional: true,
+				// Databricks defaults to on-demand instances, and to a spot_bid_max_price of -1 for spot ones
+				Computed:      true,
+				ForceNew:      true,
+				MaxItems:      1,
+				ConflictsWith: []string{"aws_attributes"},
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"availability": {
+							Type:     schema.TypeString,
+							Optional: true,
+							Computed: true,
+							ForceNew: true,
+							ValidateFunc: validation.StringInSlice([]string{
+								string(databricks.SPOT_AZURE_ClustersAzureAvailability),
+								string(databricks.ON_DEMAND_AZURE_ClustersAzureAvailability),
+							}, false),
+						},
+						"spot_bid_max_price": {
+							Type:         schema.TypeFloat,
+							Optional:     true,
+							Computed:     true,
+							ForceNew:     true,
+							ValidateFunc: validateSpotBidMaxPrice,
+						},
+					},
+				},
+			},
+			"default_tags": {
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"state": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func resourceDatabricksInstancePoolCreate(d *schema.ResourceData, m interface{}) error {
+	client := m.(*databricksClient).InstancePoolApi
+
+	request := getInstancePoolSettings(d)
+	logJSON("[DEBUG] Creating instance pool", request)
+
+	resp, _, err := client.CreateInstancePool(nil, request)
+	if err != nil {
+		return newAPIError(err)
+	}
+
+	d.SetId(resp.InstancePoolId)
+
+	return resourceDatabricksInstancePoolRead(d, m)
+}
+
+func resourceDatabricksInstancePoolUpdate(d *schema.ResourceData, m interface{}) error {
+	client := m.(*databricksClient).InstancePoolApi
+
+	request := databricks.InstancePoolsEditRequest{
+		InstancePoolId:                     d.Id(),
+		InstancePoolName:                   d.Get("instance_pool_name").(string),
+		MinIdleInstances:                   int32(d.Get("min_idle_instances").(int)),
+		MaxCapacity:                        int32(d.Get("max_capacity").(int)),
+		NodeTypeId:                         d.Get("node_type_id").(string),
+		IdleInstanceAutoterminationMinutes: int32(d.Get("idle_instance_autotermination_minutes").(int)),
+	}
+	logJSON("[DEBUG] Updating instance pool", request)
+
+	_, err := client.EditInstancePool(nil, request)
+	if err != nil {
+		return newAPIError(err)
+	}
+
+	return resourceDatabricksInstancePoolRead(d, m)
+}
+
+func resourceDatabricksInstancePoolDelete(d *schema.ResourceData, m interface{}) error {
+	client := m.(*databricksClient).InstancePoolApi
+
+	_, err := client.DeleteInstancePool(nil, databricks.InstancePoolsDeleteRequest{
+		InstancePoolId: d.Id(),
+	})
+	if err != nil {
+		return newAPIError(err)
+	}
+
+	d.SetId("")
+
+	return nil
+}
+
+func resourceDatabricksInstancePoolRead(d *schema.ResourceData, m interface{}) error {
+	client := m.(*databricksClient).InstancePoolApi
+
+	resp, _, err := client.GetInstancePool(nil, d.Id())
+	if err != nil {
+		err = newAPIError(err)
+		if isNotFoundError(err) {
+			log.Printf("[WARN] Instance pool (%s) not found, removing from state", d.Id())
+			d.SetId("")
+			return nil
+		}
+		return err
+	}
+
+	// deleted pools are still returned for a while
+	if resp.State == "DELETED" {
+		log.Printf("[WARN] Instance pool (%s) was deleted, removing from state", d.Id())
+		d.SetId("")
+		return nil
+	}
+
+	return setInstancePoolSettings(d, resp)
+}
+
+func getInstancePoolSettings(d *schema.ResourceData) databricks.InstancePoolsCreateRequest {
+	request := databricks.InstancePoolsCreateRequest{
+		InstancePoolName:                   d.Get("instance_pool_name").(string),
+		NodeTypeId:                         d.Get("node_type_id").(string),
+		IdleInstanceAutoterminationMinutes: int32(d.Get("idle_instance_autotermination_minutes").(int)),
+	}
+
+	if v, ok := d.GetOk("min_idle_instances"); ok {
+		request.MinIdleInstances = int32(v.(int))
+	}
+
+	if v, ok := d.GetOk("max_capacity"); ok {
+		request.MaxCapacity = int32(v.(int))
+	}
+
+	if v, ok := d.GetOk("preloaded_spark_versions"); ok {
+		request.PreloadedSparkVersions = toSliceString(v)
+	}
+
+	if v, ok := d.GetOk("custom_tags"); ok {
+		request.CustomTags = toMapString(v)
+	}
+
+	if v, ok := d.GetOk("enable_elastic_disk"); ok {
+		request.EnableElasticDisk = v.(bool)
+	}
+
+	if v, ok := d.GetOk("aws_attributes"); ok {
+		awsAttributes := resourceDatabricksInstancePoolExpandAwsAttributes(v.([]interface{}))
+		request.AwsAttributes = &awsAttributes
+	}
+
+	if v, ok := d.GetOk("azure_attributes"); ok {
+		azureAttributes := resourceDatabricksInstancePoolExpandAzureAttributes(v.([]interface{}))
+		request.AzureAttributes = &azureAttributes
+	}
+
+	return request
+}
+
+func setInstancePoolSettings(d *schema.ResourceData, pool databricks.InstancePoolsInstancePoolAndStats) error {
+	err := d.Set("instance_pool_name", pool.InstancePoolName)
+	if err != nil {
+		return err
+	}
+
+	err = d.Set("min_idle_instances", int(pool.MinIdleInstances))
+	if err != nil {
+		return err
+	}
+
+	err = d.Set("max_capacity", int(pool.MaxCapacity))
+	if err != nil {
+		return err
+	}
+
+	err = d.Set("idle_instance_autotermination_minutes", int(pool.IdleInstanceAutoterminationMinutes))
+	if err != nil {
+		return err
+	}
+
+	err = d.Set("node_type_id", pool.NodeTypeId)
+	if err != nil {
+		return err
+	}
+
+	err = d.Set("preloaded_spark_versions", pool.PreloadedSparkVersions)
+	if err != nil {
+		return err
+	}
+
+	err = d.Set("custom_tags", pool.CustomTags)
+	if err != nil {
+		return err
+	}
+
+	err = d.Set("enable_elastic_disk", pool.EnableElasticDisk)
+	if err != nil {
+		return err
+	}
+
+	err = d.Set("aws_attributes", resourceDatabricksInstancePoolFlattenAwsAttributes(pool.AwsAttributes))
+	if err != nil {
+		return err
+	}
+
+	err = d.Set("azure_attributes", resourceDatabricksInstancePoolFlattenAzureAttributes(pool.AzureAttributes))
+	if err != nil {
+		return err
+	}
+
+	err = d.Set("default_tags", pool.DefaultTags)
+	if err != nil {
+		return err
+	}
+
+	return d.Set("state", pool.State)
+}
+
+func resourceDatabricksInstancePoolExpandAwsAttributes(awsAttributes []interface{}) databricks.InstancePoolsAwsAttributes {
+	m := awsAttributes[0].(map[string]interface{})
+
+	result := databricks.InstancePoolsAwsAttributes{}
+
+	if v, ok := getOk(m, "availability"); ok {
+		availability := databricks.ClustersAwsAvailability(v.(string))
+		result.Availability = &availability
+	}
+
+	if v, ok := getOk(m, "zone_id"); ok {
+		result.ZoneId = v.(string)
+	}
+
+	if v, ok := getOk(m, "spot_bid_price_percent"); ok {
+		result.SpotBidPricePercent = int32(v.(int))
+	}
+
+	return result
+}
+
+func resourceDatabricksInstancePoolFlattenAwsAttributes(awsAttributes *databricks.InstancePoolsAwsAttributes) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0)
+	if awsAttributes != nil {
+		attrs := make(map[string]interface{})
+		if awsAttributes.Availability != nil {
+			attrs["availability"] = string(*awsAttributes.Availability)
+		}
+		attrs["zone_id"] = awsAttributes.ZoneId
+		attrs["spot_bid_price_percent"] = int(awsAttributes.SpotBidPricePercent)
+
+		result = append(result, attrs)
+	}
+
+	return result
+}
+
+func resourceDatabricksInstancePoolExpandAzureAttributes(azureAttributes []interface{}) databricks.InstancePoolsAzureAttributes {
+	m := azureAttributes[0].(map[string]interface{})
+
+	result := databricks.InstancePoolsAzureAttributes{}
+
+	if v, ok := getOk(m, "availability"); ok {
+		availability := databricks.ClustersAzureAvailability(v.(string))
+		result.Availability = &availability
+	}
+
+	if v, ok := getOk(m, "spot_bid_max_price"); ok {
+		result.SpotBidMaxPrice = v.(float64)
+	}
+
+	return result
+}
+
+func resourceDatabricksInstancePoolFlattenAzureAttributes(azureAttributes *databricks.InstancePoolsAzureAttributes) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0)
+	if azureAttributes != nil {
+		attrs := make(map[string]interface{})
+		if azureAttributes.Availability != nil {
+			attrs["availability"] = string(*azureAttributes.Availability)
+		}
+		attrs["spot_bid_max_price"] = azureAttributes.SpotBidMaxPrice
+
+		result = append(result, attrs)
+	}
+
+	return result
+}
@@ -0,0 +1,104 @@
+package databricks
+
+import (
+	"errors"
+	"fmt"
+	"github.com/cattail/databricks-sdk-go/databricks"
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+	"testing"
+)
+
+func TestAccDatabricksInstancePool_basic(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckDatabricksInstancePoolDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDatabricksInstancePoolConfig(0),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(
+						"databricks_instance_pool.pool", "node_type_id", "Standard_D3_v2"),
+					resource.TestCheckResourceAttr(
+						"databricks_instance_pool.pool", "min_idle_instances", "0"),
+					resource.TestCheckResourceAttrSet(
+						"databricks_cluster.cluster", "instance_pool_id"),
+					resource.TestCheckResourceAttr(
+						"databricks_cluster.cluster", "node_type_id", "Standard_D3_v2"),
+				),
+			},
+			{
+				Config: testAccDatabricksInstancePoolConfig(1),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(
+						"databricks_instance_pool.pool", "min_idle_instances", "1"),
+				),
+			},
+			{
+				ResourceName:      "databricks_instance_pool.pool",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func testAccCheckDatabricksInstancePoolDestroy(s *terraform.State) error {
+	client := testAccProvider.Meta().(*databricksClient)
+
+	instancePoolId := s.RootModule().Resources["databricks_instance_pool.pool"].Primary.ID
+
+	resp, _, err := client.InstancePoolApi.GetInstancePool(nil, instancePoolId)
+	if err == nil {
+		if resp.State == "DELETED" {
+			return nil
+		}
+		return errors.New("instance pool still exists")
+	}
+
+	if err = newAPIError(err); !isNotFoundError(err) {
+		return err
+	}
+
+	return nil
+}
+
+func testAccDatabricksInstancePoolConfig(minIdleInstances int) string {
+	return fmt.Sprintf(`
+resource "databricks_instance_pool" "pool" {
+	instance_pool_name                    = "tf-test-pool"
+	node_type_id                          = "Standard_D3_v2"
+	min_idle_instances                    = %d
+	max_capacity                          = 4
+	idle_instance_autotermination_minutes = 10
+	preloaded_spark_versions              = ["4.2.x-scala2.11"]
+}
+
+resource "databricks_cluster" "cluster" {
+	cluster_name            = "tf-test-pool-cluster"
+	spark_version           = "4.2.x-scala2.11"
+	instance_pool_id        = "${databricks_instance_pool.pool.id}"
+	num_workers             = 1
+	autotermination_minutes = 10
+	no_wait                 = true
+}
+`, minIdleInstances)
+}
+
+func TestDatabricksInstancePool_flattenAwsAttributes(t *testing.T) {
+	availability := databricks.SPOT_ClustersAwsAvailability
+	awsAttributes := resourceDatabricksInstancePoolFlattenAwsAttributes(&databricks.InstancePoolsAwsAttributes{
+		Availability:        &availability,
+		ZoneId:              "us-west-2a",
+		SpotBidPricePercent: 100,
+	})
+
+	if len(awsAttributes) != 1 || awsAttributes[0]["availability"] != "SPOT" || awsAttributes[0]["spot_bid_price_percent"] != 100 {
+		t.Fatalf("unexpected aws attributes %v", awsAttributes)
+	}
+
+	if v := resourceDatabricksInstancePoolFlattenAwsAttributes(nil); len(v) != 0 {
+		t.Fatalf("expected no aws attributes, got %v", v)
+	}
+}
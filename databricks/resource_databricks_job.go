@@ -1,6 +1,7 @@
 package databricks
 
 import (
+	"fmt"
 	"github.com/cattail/databricks-sdk-go/databricks"
 	"github.com/hashicorp/terraform/helper/schema"
 	"log"
@@ -13,6 +14,11 @@ func resourceDatabricksJob() *schema.Resource {
 		Read:   resourceDatabricksJobRead,
 		Update: resourceDatabricksJobUpdate,
 		Delete: resourceDatabricksJobDelete,
+		Importer: &schema.ResourceImporter{
+			State: resourceDatabricksJobImport,
+		},
+
+		CustomizeDiff: resourceDatabricksJobCustomizeDiff,
 
 		Schema: map[string]*schema.Schema{
 			"new_cluster": {
@@ -20,7 +26,7 @@ func resourceDatabricksJob() *schema.Resource {
 				Optional: true,
 				MaxItems: 1,
 				Elem: &schema.Resource{
-					Schema: resourceDatabricksCluster().Schema,
+					Schema: resourceDatabricksClusterSettingsSchema(),
 				},
 				ConflictsWith: []string{"existing_cluster_id"},
 			},
@@ -116,82 +122,7 @@ func resourceDatabricksJob() *schema.Resource {
 				Type:     schema.TypeList,
 				Optional: true,
 				Elem: &schema.Resource{
-					Schema: map[string]*schema.Schema{
-						"jar": {
-							Type:     schema.TypeString,
-							Optional: true,
-							//ConflictsWith: []string{"egg", "whl", "pypi", "maven", "cran"},
-						},
-						"egg": {
-							Type:     schema.TypeString,
-							Optional: true,
-							//ConflictsWith: []string{"jar", "whl", "pypi", "maven", "cran"},
-						},
-						"whl": {
-							Type:     schema.TypeString,
-							Optional: true,
-							//ConflictsWith: []string{"jar", "egg", "pypi", "maven", "cran"},
-						},
-						"pypi": {
-							Type:     schema.TypeList,
-							Optional: true,
-							//ConflictsWith: []string{"jar", "egg", "whl", "maven", "cran"},
-							MaxItems: 1,
-							Elem: &schema.Resource{
-								Schema: map[string]*schema.Schema{
-									"package": {
-										Type:     schema.TypeString,
-										Optional: true,
-									},
-									"repo": {
-										Type:     schema.TypeString,
-										Optional: true,
-									},
-								},
-							},
-						},
-						"maven": {
-							Type:     schema.TypeList,
-							Optional: true,
-							//ConflictsWith: []string{"jar", "egg", "whl", "pypi", "cran"},
-							MaxItems: 1,
-							Elem: &schema.Resource{
-								Schema: map[string]*schema.Schema{
-									"coordinates": {
-										Type:     schema.TypeString,
-										Optional: true,
-									},
-									"repo": {
-										Type:     schema.TypeString,
-										Optional: true,
-									},
-									"exclusions": {
-										Type:     schema.TypeList,
-										Optional: true,
-										Elem:     &schema.Schema{Type: schema.TypeString},
-									},
-								},
-							},
-						},
-						"cran": {
-							Type:     schema.TypeList,
-							Optional: true,
-							//ConflictsWith: []string{"jar", "egg", "whl", "pypi", "maven"},
-							MaxItems: 1,
-							Elem: &schema.Resource{
-								Schema: map[string]*schema.Schema{
-									"package": {
-										Type:     schema.TypeString,
-										Optional: true,
-									},
-									"repo": {
-										Type:     schema.TypeString,
-										Optional: true,
-									},
-								},
-							},
-						},
-					},
+					Schema: resourceDatabricksLibrarySchema(),
 				},
 			},
 			"email_notifications": {
@@ -264,15 +195,95 @@ func resourceDatabricksJob() *schema.Resource {
 	}
 }
 
+// resourceDatabricksLibrarySchema is the schema of a single library, shared by jobs and cluster libraries.
+func resourceDatabricksLibrarySchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"jar": {
+			Type:     schema.TypeString,
+			Optional: true,
+			//ConflictsWith: []string{"egg", "whl", "pypi", "maven", "cran"},
+		},
+		"egg": {
+			Type:     schema.TypeString,
+			Optional: true,
+			//ConflictsWith: []string{"jar", "whl", "pypi", "maven", "cran"},
+		},
+		"whl": {
+			Type:     schema.TypeString,
+			Optional: true,
+			//ConflictsWith: []string{"jar", "egg", "pypi", "maven", "cran"},
+		},
+		"pypi": {
+			Type:     schema.TypeList,
+			Optional: true,
+			//ConflictsWith: []string{"jar", "egg", "whl", "maven", "cran"},
+			MaxItems: 1,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"package": {
+						Type:     schema.TypeString,
+						Optional: true,
+					},
+					"repo": {
+						Type:     schema.TypeString,
+						Optional: true,
+					},
+				},
+			},
+		},
+		"maven": {
+			Type:     schema.TypeList,
+			Optional: true,
+			//ConflictsWith: []string{"jar", "egg", "whl", "pypi", "cran"},
+			MaxItems: 1,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"coordinates": {
+						Type:     schema.TypeString,
+						Optional: true,
+					},
+					"repo": {
+						Type:     schema.TypeString,
+						Optional: true,
+					},
+					"exclusions": {
+						Type:     schema.TypeList,
+						Optional: true,
+						Elem:     &schema.Schema{Type: schema.TypeString},
+					},
+				},
+			},
+		},
+		"cran": {
+			Type:     schema.TypeList,
+			Optional: true,
+			//ConflictsWith: []string{"jar", "egg", "whl", "pypi", "maven"},
+			MaxItems: 1,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"package": {
+						Type:     schema.TypeString,
+						Optional: true,
+					},
+					"repo": {
+						Type:     schema.TypeString,
+						Optional: true,
+					},
+				},
+			},
+		},
+	}
+}
+
 func resourceDatabricksJobCreate(d *schema.ResourceData, m interface{}) error {
-	client := m.(*databricks.APIClient).JobApi
+	client := m.(*databricksClient).JobApi
 
 	request := getJobSettings(d)
 	logJSON("[DEBUG] Creating job", request)
 
 	resp, _, err := client.CreateJob(nil, request)
 	if err != nil {
-		return err
+		return clusterPolicyError(jobPolicyId(d), newAPIError(err))
 	}
 
 	d.SetId(strconv.FormatInt(resp.JobId, 10))
@@ -281,7 +292,7 @@ func resourceDatabricksJobCreate(d *schema.ResourceData, m interface{}) error {
 }
 
 func resourceDatabricksJobUpdate(d *schema.ResourceData, m interface{}) error {
-	client := m.(*databricks.APIClient).JobApi
+	client := m.(*databricksClient).JobApi
 
 	settings := getJobSettings(d)
 
@@ -298,14 +309,14 @@ func resourceDatabricksJobUpdate(d *schema.ResourceData, m interface{}) error {
 
 	_, err = client.ResetJob(nil, request)
 	if err != nil {
-		return err
+		return clusterPolicyError(jobPolicyId(d), newAPIError(err))
 	}
 
 	return resourceDatabricksJobRead(d, m)
 }
 
 func resourceDatabricksJobDelete(d *schema.ResourceData, m interface{}) error {
-	client := m.(*databricks.APIClient).JobApi
+	client := m.(*databricksClient).JobApi
 
 	jobId, err := strconv.ParseInt(d.Id(), 10, 64)
 	if err != nil {
@@ -316,7 +327,7 @@ func resourceDatabricksJobDelete(d *schema.ResourceData, m interface{}) error {
 		JobId: jobId,
 	})
 	if err != nil {
-		return err
+		return newAPIError(err)
 	}
 
 	d.SetId("")
@@ -325,16 +336,17 @@ func resourceDatabricksJobDelete(d *schema.ResourceData, m interface{}) error {
 }
 
 func resourceDatabricksJobRead(d *schema.ResourceData, m interface{}) error {
-	client := m.(*databricks.APIClient).JobApi
+	client := m.(*databricksClient).JobApi
 
 	jobId, err := strconv.ParseInt(d.Id(), 10, 64)
 	if err != nil {
 		return err
 	}
 
-	resp, httpResponse, err := client.GetJob(nil, jobId)
+	resp, _, err := client.GetJob(nil, jobId)
 	if err != nil {
-		if resourceDatabricksClusterNotExistsError(httpResponse) {
+		err = newAPIError(err)
+		if isNotFoundError(err) {
 			log.Printf("[WARN] Job (%s) not found, removing from state", d.Id())
 			d.SetId("")
 			return nil
@@ -345,6 +357,33 @@ func resourceDatabricksJobRead(d *schema.ResourceData, m interface{}) error {
 	return setJobSettings(d, *resp.Settings)
 }
 
+func resourceDatabricksJobCustomizeDiff(d *schema.ResourceDiff, m interface{}) error {
+	return validateJobClusterSettings(d, workspaceCloudOf(m))
+}
+
+// validateJobClusterSettings checks the settings of the job cluster, if the job runs on one rather than on
+// an existing cluster.
+func validateJobClusterSettings(d *schema.ResourceDiff, cloud string) error {
+	if _, ok := d.GetOk("new_cluster"); !ok {
+		return nil
+	}
+
+	return validateClusterSettings(d, "new_cluster.0.", cloud)
+}
+
+// jobPolicyId returns the policy of the job cluster, if any.
+func jobPolicyId(d *schema.ResourceData) string {
+	return d.Get("new_cluster.0.policy_id").(string)
+}
+
+func resourceDatabricksJobImport(d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+	if _, err := strconv.ParseInt(d.Id(), 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid job ID %q, expected the numeric ID shown in the job URL", d.Id())
+	}
+
+	return []*schema.ResourceData{d}, nil
+}
+
 func getJobSettings(d *schema.ResourceData) databricks.JobSettings {
 	jobSettings := databricks.JobSettings{}
 
@@ -420,20 +459,31 @@ func getJobSettings(d *schema.ResourceData) databricks.JobSettings {
 }
 
 func setJobSettings(d interface{}, jobSettings databricks.JobSettings) error {
+	newCluster := make([]map[string]interface{}, 0)
 	if jobSettings.NewCluster != nil {
 		m := make(map[string]interface{})
+		// start from the state, so that settings the API does not return, such as the docker registry
+		// password, are kept and settings added by Databricks can be told apart from configured ones
+		if current, ok := getOk(d, "new_cluster"); ok {
+			if l := current.([]interface{}); len(l) > 0 && l[0] != nil {
+				for k, v := range l[0].(map[string]interface{}) {
+					m[k] = v
+				}
+			}
+		}
 		err := setClusterSettings(m, *jobSettings.NewCluster)
 		if err != nil {
 			return err
 		}
+		newCluster = append(newCluster, m)
+	}
 
-		err = set(d, "new_cluster", []map[string]interface{}{m})
-		if err != nil {
-			return err
-		}
+	err := set(d, "new_cluster", newCluster)
+	if err != nil {
+		return err
 	}
 
-	err := set(d, "existing_cluster_id", jobSettings.ExistingClusterId)
+	err = set(d, "existing_cluster_id", jobSettings.ExistingClusterId)
 	if err != nil {
 		return err
 	}
@@ -710,7 +760,7 @@ func resourceDatabricksJobFlattenLibraries(libraries []databricks.Library) []map
 			cran := make(map[string]interface{})
 			cran["package"] = library.Cran.Package_
 			cran["repo"] = library.Cran.Repo
-			item["pypi"] = []interface{}{cran}
+			item["cran"] = []interface{}{cran}
 		}
 		result[i] = item
 	}
@@ -745,7 +795,9 @@ func resourceDatabricksJobExpandEmailNotifications(d []interface{}) databricks.J
 func resourceDatabricksJobFlattenEmailNotification(emailNotification *databricks.JobEmailNotifications) []map[string]interface{} {
 	result := make([]map[string]interface{}, 0)
 
-	if emailNotification != nil {
+	// jobs created in the UI come back with an empty email_notifications object
+	if emailNotification != nil && (len(emailNotification.OnStart) > 0 || len(emailNotification.OnSuccess) > 0 ||
+		len(emailNotification.OnFailure) > 0 || emailNotification.NoAlertForSkippedRuns) {
 		item := make(map[string]interface{})
 		item["on_start"] = emailNotification.OnStart
 		item["on_success"] = emailNotification.OnSuccess
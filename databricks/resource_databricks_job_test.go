@@ -0,0 +1,104 @@
+package databricks
+
+import (
+	"github.com/cattail/databricks-sdk-go/databricks"
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/helper/schema"
+	"testing"
+)
+
+func TestResourceDatabricksJob_newClusterSchema(t *testing.T) {
+	newCluster := resourceDatabricksJob().Schema["new_cluster"].Elem.(*schema.Resource).Schema
+
+	if _, ok := newCluster["spark_version"]; !ok {
+		t.Fatal("new_cluster should share the cluster settings of databricks_cluster")
+	}
+
+	for _, k := range append(resourceDatabricksClusterManagementFields, resourceDatabricksClusterRuntimeFields...) {
+		if _, ok := newCluster[k]; ok {
+			t.Fatalf("new_cluster should not have the databricks_cluster only field %s", k)
+		}
+	}
+}
+
+func TestResourceDatabricksJobImport_invalidId(t *testing.T) {
+	d := resourceDatabricksJob().TestResourceData()
+	d.SetId("my-job")
+
+	if _, err := resourceDatabricksJobImport(d, nil); err == nil {
+		t.Fatal("expected an error for a non-numeric job ID")
+	}
+}
+
+func TestResourceDatabricksJob_validateExistingCluster(t *testing.T) {
+	raw := map[string]interface{}{
+		"name":                "my-job",
+		"existing_cluster_id": "0101-120000-brick1",
+	}
+	if _, err := testResourceDiff(t, resourceDatabricksJob(), nil, raw); err != nil {
+		t.Fatalf("a job on an existing cluster has no node type to check, got %s", err)
+	}
+
+	delete(raw, "existing_cluster_id")
+	raw["new_cluster"] = []interface{}{map[string]interface{}{"spark_version": "6.4.x-scala2.11"}}
+	if _, err := testResourceDiff(t, resourceDatabricksJob(), nil, raw); err == nil {
+		t.Fatal("expected an error for a job cluster without a node type")
+	}
+}
+
+func TestResourceDatabricksJob_validateJobClusterWorkers(t *testing.T) {
+	newCluster := map[string]interface{}{
+		"spark_version": "6.4.x-scala2.11",
+		"node_type_id":  "i3.xlarge",
+		"num_workers":   config.UnknownVariableValue,
+	}
+	raw := map[string]interface{}{
+		"name":        "my-job",
+		"new_cluster": []interface{}{newCluster},
+	}
+	if _, err := testResourceDiff(t, resourceDatabricksJob(), nil, raw); err != nil {
+		t.Fatalf("num_workers only known at apply time should be accepted, got %s", err)
+	}
+
+	newCluster["num_workers"] = 0
+	if _, err := testResourceDiff(t, resourceDatabricksJob(), nil, raw); err != nil {
+		t.Fatalf("expected a job cluster without workers to be accepted, got %s", err)
+	}
+}
+
+func TestResourceDatabricksJobFlattenLibraries(t *testing.T) {
+	libraries := resourceDatabricksJobFlattenLibraries([]databricks.Library{
+		{Jar: "dbfs:/FileStore/jars/some.jar"},
+		{Cran: &databricks.RCranLibrary{Package_: "forecast"}},
+	})
+
+	if len(libraries) != 2 {
+		t.Fatalf("expected 2 libraries, got %d", len(libraries))
+	}
+
+	if libraries[0]["jar"] != "dbfs:/FileStore/jars/some.jar" {
+		t.Fatalf("unexpected jar library %v", libraries[0])
+	}
+
+	if _, ok := libraries[1]["pypi"]; ok {
+		t.Fatalf("a CRAN library must not be flattened as PyPI: %v", libraries[1])
+	}
+
+	cran, ok := libraries[1]["cran"].([]interface{})
+	if !ok || cran[0].(map[string]interface{})["package"] != "forecast" {
+		t.Fatalf("unexpected CRAN library %v", libraries[1])
+	}
+}
+
+func TestResourceDatabricksJobFlattenEmailNotification(t *testing.T) {
+	if v := resourceDatabricksJobFlattenEmailNotification(&databricks.JobEmailNotifications{}); len(v) != 0 {
+		t.Fatalf("an empty email_notifications object should not be flattened, got %v", v)
+	}
+
+	v := resourceDatabricksJobFlattenEmailNotification(&databricks.JobEmailNotifications{
+		OnFailure: []string{"somebody@example.com"},
+	})
+	if len(v) != 1 || v[0]["on_failure"].([]string)[0] != "somebody@example.com" {
+		t.Fatalf("unexpected email notifications %v", v)
+	}
+}
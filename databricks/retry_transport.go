@@ -0,0 +1,139 @@
+package databricks
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultMaxRetries   = 5
+	defaultRetryTimeout = "5m"
+
+	retryMinBackoff = 1 * time.Second
+	retryMaxBackoff = 30 * time.Second
+)
+
+// retryTransport retries requests that were rate limited (429) or hit a transient server error (5xx).
+// POST requests, which create clusters and jobs among others, are only retried when the server did not
+// process them (429 and 503), as retrying after any other error could create them twice.
+// Retries back off exponentially with full jitter, unless the server asks for a delay with Retry-After.
+// Once maxRetries or the overall timeout is exhausted, the last response is returned unchanged so that
+// callers still see the API error.
+type retryTransport struct {
+	transport  http.RoundTripper
+	maxRetries int
+	timeout    time.Duration
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+func newRetryTransport(transport http.RoundTripper, maxRetries int, timeout time.Duration) *retryTransport {
+	return &retryTransport{
+		transport:  transport,
+		maxRetries: maxRetries,
+		timeout:    timeout,
+		minBackoff: retryMinBackoff,
+		maxBackoff: retryMaxBackoff,
+	}
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// the body has to be replayed on every attempt
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	deadline := time.Now().Add(t.timeout)
+
+	for attempt := 0; ; attempt++ {
+		attemptReq := cloneRequest(req)
+		if body != nil {
+			attemptReq.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+
+		resp, err := t.transport.RoundTrip(attemptReq)
+		if err != nil || !isRetryable(req.Method, resp.StatusCode) || attempt >= t.maxRetries {
+			return resp, err
+		}
+
+		wait := t.backoff(attempt, resp)
+		if time.Now().Add(wait).After(deadline) {
+			log.Printf("[WARN] %s %s returned %s, giving up as the retry timeout of %s would be exceeded",
+				req.Method, req.URL.Path, resp.Status, t.timeout)
+			return resp, nil
+		}
+
+		log.Printf("[DEBUG] %s %s returned %s, retrying in %s (retry %d of %d)",
+			req.Method, req.URL.Path, resp.Status, wait, attempt+1, t.maxRetries)
+
+		// the response is discarded, drain it so the connection can be reused
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// backoff returns the delay before the given retry, preferring the server's Retry-After header.
+func (t *retryTransport) backoff(attempt int, resp *http.Response) time.Duration {
+	if wait, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+		return wait
+	}
+
+	ceiling := t.minBackoff << uint(attempt)
+	if ceiling <= 0 || ceiling > t.maxBackoff {
+		ceiling = t.maxBackoff
+	}
+
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+func isRetryable(method string, statusCode int) bool {
+	if statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable {
+		return true
+	}
+
+	// a 500, 502 or 504 may come after the server already applied a request that is not idempotent
+	if method == http.MethodPost {
+		return false
+	}
+
+	return statusCode >= 500 && statusCode != http.StatusNotImplemented
+}
+
+// parseRetryAfter accepts both forms of the Retry-After header: a number of seconds or an HTTP date.
+func parseRetryAfter(header string) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(header); err == nil {
+		wait := time.Until(date)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+
+	return 0, false
+}
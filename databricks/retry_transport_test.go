@@ -0,0 +1,180 @@
+package databricks
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testRetryServer answers with the given status codes in order, then with 200 OK, and checks that the
+// request body survives every retry.
+func testRetryServer(t *testing.T, statuses []int, retryAfter string) (*httptest.Server, *int32) {
+	var requests int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+
+		body, _ := ioutil.ReadAll(r.Body)
+		if string(body) != `{"cluster_id":"1234"}` {
+			t.Errorf("request %d had body %q", n, body)
+		}
+
+		if int(n) <= len(statuses) {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			w.WriteHeader(statuses[n-1])
+			fmt.Fprint(w, `{"error_code":"REQUEST_LIMIT_EXCEEDED","message":"Too many requests"}`)
+			return
+		}
+
+		fmt.Fprint(w, `{}`)
+	}))
+
+	return server, &requests
+}
+
+func testRetryClient(maxRetries int, timeout time.Duration) *http.Client {
+	transport := newRetryTransport(http.DefaultTransport, maxRetries, timeout)
+	transport.minBackoff = time.Millisecond
+	transport.maxBackoff = 10 * time.Millisecond
+	return &http.Client{Transport: transport}
+}
+
+func testRetryPost(t *testing.T, client *http.Client, url string) *http.Response {
+	return testRetryRequest(t, client, http.MethodPost, url)
+}
+
+func testRetryRequest(t *testing.T, client *http.Client, method, url string) *http.Response {
+	req, err := http.NewRequest(method, url, strings.NewReader(`{"cluster_id":"1234"}`))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func TestRetryTransport_retriesRateLimitAndServerErrors(t *testing.T) {
+	server, requests := testRetryServer(t, []int{429, 503, 500}, "")
+	defer server.Close()
+
+	resp := testRetryRequest(t, testRetryClient(5, time.Minute), http.MethodGet, server.URL)
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 after retries, got %d", resp.StatusCode)
+	}
+
+	if n := atomic.LoadInt32(requests); n != 4 {
+		t.Fatalf("expected 4 requests, got %d", n)
+	}
+}
+
+func TestRetryTransport_retriesPostWhenNotProcessed(t *testing.T) {
+	server, requests := testRetryServer(t, []int{429, 503}, "")
+	defer server.Close()
+
+	resp := testRetryPost(t, testRetryClient(5, time.Minute), server.URL)
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 after retries, got %d", resp.StatusCode)
+	}
+
+	if n := atomic.LoadInt32(requests); n != 3 {
+		t.Fatalf("expected 3 requests, got %d", n)
+	}
+}
+
+func TestRetryTransport_doesNotRetryPostOnServerError(t *testing.T) {
+	server, requests := testRetryServer(t, []int{500}, "")
+	defer server.Close()
+
+	resp := testRetryPost(t, testRetryClient(5, time.Minute), server.URL)
+
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", resp.StatusCode)
+	}
+
+	if n := atomic.LoadInt32(requests); n != 1 {
+		t.Fatalf("a POST may have been applied before the 500, expected a single request, got %d", n)
+	}
+}
+
+func TestRetryTransport_givesUpAfterMaxRetries(t *testing.T) {
+	server, requests := testRetryServer(t, []int{503, 503, 503, 503}, "")
+	defer server.Close()
+
+	resp := testRetryPost(t, testRetryClient(2, time.Minute), server.URL)
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected the last 503 to be returned, got %d", resp.StatusCode)
+	}
+
+	if n := atomic.LoadInt32(requests); n != 3 {
+		t.Fatalf("expected 1 request and 2 retries, got %d requests", n)
+	}
+}
+
+func TestRetryTransport_doesNotRetryClientErrors(t *testing.T) {
+	server, requests := testRetryServer(t, []int{400}, "")
+	defer server.Close()
+
+	resp := testRetryPost(t, testRetryClient(5, time.Minute), server.URL)
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", resp.StatusCode)
+	}
+
+	if n := atomic.LoadInt32(requests); n != 1 {
+		t.Fatalf("expected a single request, got %d", n)
+	}
+}
+
+func TestRetryTransport_honoursRetryAfterWithinTimeout(t *testing.T) {
+	// the server asks for a delay longer than the retry timeout, so no retry is attempted
+	server, requests := testRetryServer(t, []int{429}, "120")
+	defer server.Close()
+
+	start := time.Now()
+	resp := testRetryPost(t, testRetryClient(5, time.Minute), server.URL)
+
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", resp.StatusCode)
+	}
+
+	if n := atomic.LoadInt32(requests); n != 1 {
+		t.Fatalf("expected a single request, got %d", n)
+	}
+
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("should not have waited for Retry-After, took %s", elapsed)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if wait, ok := parseRetryAfter("7"); !ok || wait != 7*time.Second {
+		t.Fatalf("expected 7s, got %s, %v", wait, ok)
+	}
+
+	date := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if wait, ok := parseRetryAfter(date); !ok || wait < 59*time.Minute || wait > time.Hour {
+		t.Fatalf("expected about an hour, got %s, %v", wait, ok)
+	}
+
+	if _, ok := parseRetryAfter(""); ok {
+		t.Fatal("an empty header should not be parsed")
+	}
+
+	if _, ok := parseRetryAfter("soon"); ok {
+		t.Fatal("an invalid header should not be parsed")
+	}
+}
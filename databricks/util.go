@@ -1,11 +1,16 @@
 package databricks
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"github.com/cattail/databricks-sdk-go/databricks"
+	"github.com/hashicorp/terraform/helper/resource"
 	"github.com/hashicorp/terraform/helper/schema"
 	"log"
 	"reflect"
+	"sync"
+	"time"
 )
 
 func find(source []interface{}, predict interface{}) bool {
@@ -17,6 +22,14 @@ func find(source []interface{}, predict interface{}) bool {
 	return false
 }
 
+func toSliceInterface(d []string) []interface{} {
+	result := make([]interface{}, len(d))
+	for i, v := range d {
+		result[i] = v
+	}
+	return result
+}
+
 func toMapString(d interface{}) map[string]string {
 	result := make(map[string]string)
 	for k, v := range d.(map[string]interface{}) {
@@ -25,6 +38,20 @@ func toMapString(d interface{}) map[string]string {
 	return result
 }
 
+// hasMapKey tells whether key is in d, a map read from the schema or built by the provider.
+func hasMapKey(d interface{}, key string) bool {
+	switch m := d.(type) {
+	case map[string]interface{}:
+		_, ok := m[key]
+		return ok
+	case map[string]string:
+		_, ok := m[key]
+		return ok
+	default:
+		return false
+	}
+}
+
 func toSliceMapString(d interface{}) []map[string]string {
 	c := d.([]interface{})
 	result := make([]map[string]string, len(c))
@@ -88,6 +115,28 @@ func set(d interface{}, key string, value interface{}) error {
 	}
 }
 
+func validateDuration(v interface{}, k string) (ws []string, errors []error) {
+	if _, err := time.ParseDuration(v.(string)); err != nil {
+		errors = append(errors, fmt.Errorf("%q must be a duration such as \"30s\" or \"5m\": %s", k, err))
+	}
+	return
+}
+
+// suppressEquivalentJSON ignores differences in formatting and key order between two JSON documents.
+func suppressEquivalentJSON(k, old, new string, d *schema.ResourceData) bool {
+	var oldValue, newValue interface{}
+
+	if err := json.Unmarshal([]byte(old), &oldValue); err != nil {
+		return false
+	}
+
+	if err := json.Unmarshal([]byte(new), &newValue); err != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(oldValue, newValue)
+}
+
 func logJSON(message string, d interface{}) {
 	str, err := json.Marshal(d)
 	if err != nil {
@@ -98,6 +147,43 @@ func logJSON(message string, d interface{}) {
 	log.Printf("%s: %s\n", message, str)
 }
 
+// waitForState polls refresh until it reports one of the target states, failing on any state that is neither
+// pending nor a target, or once timeout passes. It returns the last result of refresh even when waiting
+// fails, so that callers can explain the failure. refresh gets a context that is cancelled once waiting stops.
+func waitForState(pending, target []string, timeout time.Duration, refresh func(ctx context.Context) (interface{}, string, error)) (interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// the refresh function runs in its own goroutine and may still be running after a timeout
+	var mu sync.Mutex
+	var last interface{}
+
+	conf := &resource.StateChangeConf{
+		Pending:    pending,
+		Target:     target,
+		Timeout:    timeout,
+		MinTimeout: 5 * time.Second,
+		Refresh: func() (interface{}, string, error) {
+			result, state, err := refresh(ctx)
+			if err != nil {
+				return nil, "", err
+			}
+
+			mu.Lock()
+			last = result
+			mu.Unlock()
+
+			return result, state, nil
+		},
+	}
+
+	_, err := conf.WaitForState()
+
+	mu.Lock()
+	defer mu.Unlock()
+	return last, err
+}
+
 // hack to convert struct NewCluster to struct ClusterInfo
 func convertClusterInfoToSettings(clusterInfo databricks.ClustersClusterInfo) (*databricks.NewCluster, error) {
 	clusterSettings := databricks.NewCluster{}